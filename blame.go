@@ -0,0 +1,98 @@
+package codeowners
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Blame describes the commit that last changed a line of a file.
+type Blame struct {
+	// Commit is the full hash of the commit. For lines that haven't been
+	// committed yet, it's all zeroes.
+	Commit      string
+	Author      string
+	AuthorEmail string
+	AuthorTime  time.Time
+	// Summary is the first line of the commit message.
+	Summary string
+}
+
+// Uncommitted reports whether the line has been changed in the working tree
+// but not committed yet. Git reports these with a hash of all zeroes, which is
+// 64 characters long rather than 40 in SHA-256 repositories.
+func (b Blame) Uncommitted() bool {
+	return b.Commit != "" && strings.Trim(b.Commit, "0") == ""
+}
+
+// BlameRule finds the commit that last changed the line a rule was parsed
+// from, using git blame on the CODEOWNERS file at path (relative to the root of
+// the repository). If rev is empty, the file in the working tree is blamed;
+// otherwise the file as it was at that revision is. The rule must have been
+// parsed from that version of the file for the result to be meaningful.
+func (r *Repository) BlameRule(rev, path string, rule *Rule) (*Blame, error) {
+	if rule.LineNumber < 1 {
+		return nil, fmt.Errorf("rule has no line number")
+	}
+
+	lineRange := fmt.Sprintf("%d,%d", rule.LineNumber, rule.LineNumber)
+	args := []string{"blame", "--porcelain", "-L", lineRange}
+	if rev != "" {
+		if err := checkRevision(rev); err != nil {
+			return nil, err
+		}
+		args = append(args, rev)
+	}
+	args = append(args, "--", strings.TrimPrefix(path, "/"))
+
+	output, err := r.git(args...)
+	if err != nil {
+		return nil, err
+	}
+	return parseBlamePorcelain(output)
+}
+
+// parseBlamePorcelain parses the output of git blame --porcelain for a single
+// line.
+func parseBlamePorcelain(output []byte) (*Blame, error) {
+	scanner := bufio.NewScanner(bytes.NewReader(output))
+	if !scanner.Scan() {
+		return nil, fmt.Errorf("empty blame output")
+	}
+
+	// The first line is "<hash> <original line> <final line> <line count>"
+	header := strings.Fields(scanner.Text())
+	if len(header) < 3 {
+		return nil, fmt.Errorf("invalid blame header %q", scanner.Text())
+	}
+	blame := &Blame{Commit: header[0]}
+
+	// Commit information follows as "<key> <value>" lines, and the line
+	// content is prefixed with a tab
+	for scanner.Scan() {
+		line := scanner.Text()
+		if strings.HasPrefix(line, "\t") {
+			break
+		}
+
+		key, value, _ := strings.Cut(line, " ")
+		switch key {
+		case "author":
+			blame.Author = value
+		case "author-mail":
+			blame.AuthorEmail = strings.TrimSuffix(strings.TrimPrefix(value, "<"), ">")
+		case "author-time":
+			seconds, err := strconv.ParseInt(value, 10, 64)
+			if err != nil {
+				return nil, fmt.Errorf("invalid author time %q", value)
+			}
+			blame.AuthorTime = time.Unix(seconds, 0).UTC()
+		case "summary":
+			blame.Summary = value
+		}
+	}
+	return blame, scanner.Err()
+}
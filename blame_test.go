@@ -0,0 +1,88 @@
+package codeowners
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBlameRule(t *testing.T) {
+	root := initTestRepository(t)
+	writeTestFile(t, filepath.Join(root, ".github", "CODEOWNERS"), "* @org/everyone\n/src/ @org/eng\n")
+	runGit(t, root, "add", ".")
+	runGit(t, root, "commit", "--quiet", "-m", "Add CODEOWNERS", "--date", "2021-03-04T05:06:07Z")
+
+	writeTestFile(t, filepath.Join(root, ".github", "CODEOWNERS"), "* @org/everyone\n/src/ @org/platform\n")
+	runGit(t, root, "commit", "--quiet", "-am", "Hand src over to platform", "--date", "2022-01-02T03:04:05Z")
+
+	writeTestFile(t, filepath.Join(root, ".github", "CODEOWNERS"), "* @org/everyone\n/src/ @org/platform\n/docs/ @org/docs\n")
+
+	repo, err := FindRepository(root)
+	require.NoError(t, err)
+	path, err := repo.FindFileAtStandardLocation("")
+	require.NoError(t, err)
+	require.Equal(t, ".github/CODEOWNERS", path)
+
+	ruleset, err := LoadFile(filepath.Join(root, path))
+	require.NoError(t, err)
+
+	blame, err := repo.BlameRule("", path, &ruleset[0])
+	require.NoError(t, err)
+	assert.Equal(t, "Add CODEOWNERS", blame.Summary)
+	assert.Equal(t, "Test", blame.Author)
+	assert.Equal(t, "test@example.com", blame.AuthorEmail)
+	assert.Equal(t, time.Date(2021, 3, 4, 5, 6, 7, 0, time.UTC), blame.AuthorTime)
+	assert.False(t, blame.Uncommitted())
+
+	blame, err = repo.BlameRule("", path, &ruleset[1])
+	require.NoError(t, err)
+	assert.Equal(t, "Hand src over to platform", blame.Summary)
+
+	blame, err = repo.BlameRule("", path, &ruleset[2])
+	require.NoError(t, err)
+	assert.True(t, blame.Uncommitted())
+
+	// Blaming at a revision uses the file as it was then
+	ruleset, err = repo.LoadFileAtRevision("HEAD~1", path)
+	require.NoError(t, err)
+	blame, err = repo.BlameRule("HEAD~1", path, &ruleset[1])
+	require.NoError(t, err)
+	assert.Equal(t, "Add CODEOWNERS", blame.Summary)
+}
+
+func TestParseBlamePorcelain(t *testing.T) {
+	output := "" +
+		"1f2e3d4c5b6a79881f2e3d4c5b6a79881f2e3d4c 3 5 1\n" +
+		"author Jane Doe\n" +
+		"author-mail <jane@example.com>\n" +
+		"author-time 1700000000\n" +
+		"author-tz +0100\n" +
+		"committer Jane Doe\n" +
+		"summary Give docs to the writers\n" +
+		"filename CODEOWNERS\n" +
+		"\t/docs/ @org/writers\n"
+
+	blame, err := parseBlamePorcelain([]byte(output))
+	require.NoError(t, err)
+	assert.Equal(t, &Blame{
+		Commit:      "1f2e3d4c5b6a79881f2e3d4c5b6a79881f2e3d4c",
+		Author:      "Jane Doe",
+		AuthorEmail: "jane@example.com",
+		AuthorTime:  time.Unix(1700000000, 0).UTC(),
+		Summary:     "Give docs to the writers",
+	}, blame)
+
+	_, err = parseBlamePorcelain(nil)
+	assert.Error(t, err)
+}
+
+func TestBlameUncommitted(t *testing.T) {
+	assert.True(t, Blame{Commit: strings.Repeat("0", 40)}.Uncommitted())
+	assert.True(t, Blame{Commit: strings.Repeat("0", 64)}.Uncommitted())
+	assert.False(t, Blame{Commit: "1f2e3d4c5b6a79881f2e3d4c5b6a79881f2e3d4c"}.Uncommitted())
+	assert.False(t, Blame{}.Uncommitted())
+}
@@ -0,0 +1,126 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+
+	"github.com/hmarr/codeowners"
+	flag "github.com/spf13/pflag"
+)
+
+// runBlame implements the blame subcommand, which shows the rule that decides
+// the ownership of each path along with the commit that last changed it.
+func runBlame(args []string) error {
+	var (
+		codeownersPath string
+		rev            string
+		dialectName    string
+		matchOpts      matchOptions
+		helpFlag       bool
+	)
+	flags := flag.NewFlagSet("blame", flag.ContinueOnError)
+	flags.StringVarP(&codeownersPath, "file", "f", "", "CODEOWNERS file path")
+	flags.StringVarP(&rev, "rev", "r", "", "blame the CODEOWNERS file as of a git revision")
+	flags.StringVar(&dialectName, "dialect", "", "CODEOWNERS dialect: github, gitlab, or bitbucket (detected by default)")
+	flags.BoolVarP(&matchOpts.ignoreCase, "ignore-case", "i", false, "match paths regardless of case")
+	flags.BoolVarP(&helpFlag, "help", "h", false, "show this help message")
+	flags.Usage = func() {
+		fmt.Fprintf(os.Stderr, "usage: codeowners blame [options] <path>...\n\n")
+		fmt.Fprintf(os.Stderr, "Shows the CODEOWNERS rule that matches each path, and the commit that last\n")
+		fmt.Fprintf(os.Stderr, "changed that rule. In GitLab files with sections, the matching rule in each\n")
+		fmt.Fprintf(os.Stderr, "section is shown.\n\n")
+		flags.PrintDefaults()
+	}
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	if helpFlag {
+		flags.Usage()
+		return nil
+	}
+	if flags.NArg() == 0 {
+		flags.Usage()
+		return fmt.Errorf("expected at least one path")
+	}
+
+	var dialect codeowners.Dialect
+	if dialectName != "" {
+		var err error
+		if dialect, err = codeowners.ParseDialect(dialectName); err != nil {
+			return err
+		}
+	}
+
+	repo, err := codeowners.FindRepository(".")
+	if err != nil {
+		return err
+	}
+
+	// Blame needs to know which file the rules came from
+	if codeownersPath == "" {
+		codeownersPath, err = repo.FindFileAtStandardLocation(rev)
+		if err != nil {
+			return err
+		}
+		if codeownersPath == "" {
+			return fmt.Errorf("could not find CODEOWNERS file at any of the standard locations")
+		}
+	} else {
+		codeownersPath, err = repoRelativePath(repo, codeownersPath)
+		if err != nil {
+			return err
+		}
+	}
+
+	ruleset, err := loadCodeowners(repo, filepath.Join(repo.Root, codeownersPath), rev, dialect, matchOpts)
+	if err != nil {
+		return err
+	}
+
+	out := bufio.NewWriter(os.Stdout)
+	defer out.Flush()
+
+	for _, path := range flags.Args() {
+		repoPath, err := repoRelativePath(repo, path)
+		if err != nil {
+			return err
+		}
+		rules, err := ruleset.MatchSections(repoPath)
+		if err != nil {
+			return err
+		}
+
+		fmt.Fprintln(out, path)
+		if len(rules) == 0 {
+			fmt.Fprintln(out, "    rule:   (none, so the path is unowned)")
+			continue
+		}
+
+		for _, rule := range rules {
+			blame, err := repo.BlameRule(rev, codeownersPath, rule)
+			if err != nil {
+				return err
+			}
+			printBlame(out, codeownersPath, rule, blame)
+		}
+	}
+	return nil
+}
+
+func printBlame(out io.Writer, codeownersPath string, rule *codeowners.Rule, blame *codeowners.Blame) {
+	section := ""
+	if rule.Section != nil {
+		section = "[" + rule.Section.Name + "] "
+	}
+	fmt.Fprintf(out, "    rule:   %s%s %s (%s:%d)\n", section, rule.RawPattern(), formatOwners(rule.Owners), codeownersPath, rule.LineNumber)
+	if blame.Uncommitted() {
+		fmt.Fprintln(out, "    commit: (not committed yet)")
+		return
+	}
+	fmt.Fprintf(out, "    commit: %.12s %s %s <%s>\n", blame.Commit, blame.AuthorTime.Format("2006-01-02"), blame.Author, blame.AuthorEmail)
+	fmt.Fprintf(out, "            %s\n", blame.Summary)
+}
@@ -0,0 +1,154 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/hmarr/codeowners"
+	flag "github.com/spf13/pflag"
+)
+
+const defaultCompiledHeader = "This file is generated by `codeowners compile` from the CODEOWNERS files in\nthe repository. Do not edit it by hand."
+
+// runCompile implements the compile subcommand, which merges the CODEOWNERS
+// files in subdirectories into a single file for the root of the repository.
+func runCompile(args []string) error {
+	var (
+		outputPath string
+		header     string
+		check      bool
+		helpFlag   bool
+	)
+	flags := flag.NewFlagSet("compile", flag.ContinueOnError)
+	flags.StringVar(&outputPath, "output", "", "write to this file rather than standard output")
+	flags.StringVar(&header, "header", defaultCompiledHeader, "comment to add to the top of the file (empty for none)")
+	flags.BoolVar(&check, "check", false, "check the output file is up to date rather than writing it")
+	flags.BoolVarP(&helpFlag, "help", "h", false, "show this help message")
+	flags.Usage = func() {
+		fmt.Fprintf(os.Stderr, "usage: codeowners compile [options]\n\n")
+		fmt.Fprintf(os.Stderr, "Merges the CODEOWNERS files in the repository into a single file that GitHub\n")
+		fmt.Fprintf(os.Stderr, "understands. A CODEOWNERS file in a subdirectory applies to that directory,\n")
+		fmt.Fprintf(os.Stderr, "and its rules take precedence over those in files further up. The output file\n")
+		fmt.Fprintf(os.Stderr, "itself is never used as an input.\n\n")
+		flags.PrintDefaults()
+	}
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	if helpFlag {
+		flags.Usage()
+		return nil
+	}
+	if check && outputPath == "" {
+		return fmt.Errorf("--check requires --output")
+	}
+
+	repo, err := codeowners.FindRepository(".")
+	if err != nil {
+		return err
+	}
+
+	exclude := ""
+	if outputPath != "" {
+		if exclude, err = repoRelativePath(repo, outputPath); err != nil {
+			return err
+		}
+	}
+	rulesets, err := loadNestedRulesets(repo, exclude, "", matchOptions{})
+	if err != nil {
+		return err
+	}
+	sort.SliceStable(rulesets, func(i, j int) bool {
+		return rulesets[i].Dir < rulesets[j].Dir
+	})
+
+	var buf bytes.Buffer
+	if header != "" {
+		for _, line := range strings.Split(header, "\n") {
+			fmt.Fprintf(&buf, "# %s\n", line)
+		}
+	}
+	for i, scoped := range rulesets {
+		rebased, err := scoped.Ruleset.Rebase(scoped.Dir)
+		if err != nil {
+			return fmt.Errorf("%s: %w", scoped.Path, err)
+		}
+		if header != "" || i > 0 {
+			buf.WriteString("\n")
+		}
+		fmt.Fprintf(&buf, "# From %s\n", scoped.Path)
+		if err := codeowners.WriteFile(&buf, rebased); err != nil {
+			return err
+		}
+	}
+
+	if check {
+		existing, err := os.ReadFile(outputPath)
+		if err != nil && !os.IsNotExist(err) {
+			return err
+		}
+		if !bytes.Equal(existing, buf.Bytes()) {
+			return fmt.Errorf("%s is out of date, run codeowners compile to update it", outputPath)
+		}
+		return nil
+	}
+
+	if outputPath != "" {
+		return os.WriteFile(outputPath, buf.Bytes(), 0o644)
+	}
+	_, err = buf.WriteTo(os.Stdout)
+	return err
+}
+
+// loadNestedRulesets loads every CODEOWNERS file in the repository that isn't
+// ignored by git, skipping the file at the exclude path (relative to the root
+// of the repository) if it's not empty. If dialect is empty, each file's
+// dialect is detected from its location.
+func loadNestedRulesets(repo *codeowners.Repository, exclude string, dialect codeowners.Dialect, matchOpts matchOptions) ([]codeowners.ScopedRuleset, error) {
+	// Paths need to be relative to the current directory for gitFiles
+	cwd, err := os.Getwd()
+	if err != nil {
+		return nil, err
+	}
+	root, err := filepath.Rel(cwd, repo.Root)
+	if err != nil {
+		return nil, err
+	}
+	files, err := gitFiles(repo, []string{root}, "", true)
+	if err != nil {
+		return nil, err
+	}
+
+	var paths []string
+	for _, file := range files {
+		path, err := repoRelativePath(repo, file)
+		if err != nil {
+			return nil, err
+		}
+		if path != exclude {
+			paths = append(paths, path)
+		}
+	}
+
+	options := []codeowners.ParseOption{
+		codeowners.WithDialect(dialect),
+		codeowners.WithIgnoreCase(matchOpts.ignoreCase),
+		codeowners.WithUnicodeNormalization(matchOpts.normalizeUnicode),
+	}
+	if matchOpts.extendedPatterns {
+		options = append(options, codeowners.WithExtendedPatterns(true))
+	}
+	rulesets, err := codeowners.LoadNestedFiles(repo.Root, codeowners.NestedFilePaths(paths), options...)
+	if err != nil {
+		return nil, err
+	}
+	if len(rulesets) == 0 {
+		return nil, fmt.Errorf("could not find any CODEOWNERS files")
+	}
+	return rulesets, nil
+}
@@ -0,0 +1,91 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+
+	"github.com/hmarr/codeowners"
+	flag "github.com/spf13/pflag"
+)
+
+// runConvert implements the convert subcommand, which translates a CODEOWNERS
+// file from one host's dialect to another's.
+func runConvert(args []string) error {
+	var (
+		codeownersPath string
+		fromName       string
+		toName         string
+		outputPath     string
+		helpFlag       bool
+	)
+	flags := flag.NewFlagSet("convert", flag.ContinueOnError)
+	flags.StringVarP(&codeownersPath, "file", "f", "", "CODEOWNERS file path")
+	flags.StringVar(&fromName, "from", "", "dialect to convert from (default detected from the file's location)")
+	flags.StringVar(&toName, "to", "", "dialect to convert to: github, gitlab, or bitbucket")
+	flags.StringVar(&outputPath, "output", "", "write to this file rather than standard output")
+	flags.BoolVarP(&helpFlag, "help", "h", false, "show this help message")
+	flags.Usage = func() {
+		fmt.Fprintf(os.Stderr, "usage: codeowners convert [options] --to <dialect>\n\n")
+		fmt.Fprintf(os.Stderr, "Converts a CODEOWNERS file between the GitHub, GitLab, and Bitbucket dialects,\n")
+		fmt.Fprintf(os.Stderr, "rewriting patterns that match differently. Anything that can't be converted\n")
+		fmt.Fprintf(os.Stderr, "exactly, such as GitLab sections, is reported as a warning.\n\n")
+		flags.PrintDefaults()
+	}
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	if helpFlag {
+		flags.Usage()
+		return nil
+	}
+	if toName == "" {
+		flags.Usage()
+		return fmt.Errorf("--to is required")
+	}
+	if flags.NArg() > 0 {
+		flags.Usage()
+		return fmt.Errorf("unexpected arguments")
+	}
+	to, err := codeowners.ParseDialect(toName)
+	if err != nil {
+		return err
+	}
+
+	repo, _ := codeowners.FindRepository(".")
+	var from codeowners.Dialect
+	if fromName != "" {
+		if from, err = codeowners.ParseDialect(fromName); err != nil {
+			return err
+		}
+	} else {
+		path := codeownersPath
+		if path == "" && repo != nil {
+			if path, err = repo.FindFileAtStandardLocation(""); err != nil {
+				return err
+			}
+		}
+		from = codeowners.DetectDialect(path)
+	}
+
+	ruleset, err := loadCodeowners(repo, codeownersPath, "", from, matchOptions{})
+	if err != nil {
+		return err
+	}
+	converted, warnings, err := ruleset.Convert(from, to)
+	if err != nil {
+		return err
+	}
+	printWarnings(warnings)
+
+	var buf bytes.Buffer
+	if err := codeowners.WriteFile(&buf, converted); err != nil {
+		return err
+	}
+	if outputPath != "" {
+		return os.WriteFile(outputPath, buf.Bytes(), 0o644)
+	}
+	_, err = buf.WriteTo(os.Stdout)
+	return err
+}
@@ -0,0 +1,305 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/hmarr/codeowners"
+	flag "github.com/spf13/pflag"
+)
+
+// runDiff implements the diff subcommand, which shows how ownership changes
+// between two versions of a CODEOWNERS file.
+func runDiff(args []string) error {
+	var (
+		codeownersPath string
+		format         string
+		exitCode       bool
+		rules          bool
+		helpFlag       bool
+	)
+	flags := flag.NewFlagSet("diff", flag.ContinueOnError)
+	flags.StringVarP(&codeownersPath, "file", "f", "", "CODEOWNERS file path (at each revision)")
+	flags.StringVar(&format, "format", "text", "output format: text, markdown, or json")
+	flags.BoolVar(&exitCode, "exit-code", false, "exit with status 1 if ownership changed")
+	flags.BoolVar(&rules, "rules", false, "compare rules rather than the ownership of files")
+	flags.BoolVarP(&helpFlag, "help", "h", false, "show this help message")
+	flags.Usage = func() {
+		fmt.Fprintf(os.Stderr, "usage: codeowners diff [options] <base-rev> [<head-rev>]\n\n")
+		fmt.Fprintf(os.Stderr, "Shows files whose owners differ between two revisions. If the head revision\n")
+		fmt.Fprintf(os.Stderr, "is omitted, the working tree is used. With --rules, shows the rules that were\n")
+		fmt.Fprintf(os.Stderr, "added, removed, moved, or given new owners instead.\n\n")
+		flags.PrintDefaults()
+	}
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	if helpFlag {
+		flags.Usage()
+		return nil
+	}
+	if flags.NArg() < 1 || flags.NArg() > 2 {
+		flags.Usage()
+		return fmt.Errorf("expected one or two revisions")
+	}
+
+	repo, err := codeowners.FindRepository(".")
+	if err != nil {
+		return err
+	}
+
+	baseRev, headRev := flags.Arg(0), flags.Arg(1)
+	oldRuleset, err := loadCodeowners(repo, codeownersPath, baseRev, "", matchOptions{})
+	if err != nil {
+		return fmt.Errorf("loading base CODEOWNERS: %w", err)
+	}
+	newRuleset, err := loadCodeowners(repo, codeownersPath, headRev, "", matchOptions{})
+	if err != nil {
+		return fmt.Errorf("loading head CODEOWNERS: %w", err)
+	}
+
+	if rules {
+		changes := codeowners.DiffRulesets(oldRuleset, newRuleset)
+		return printDiff(format, exitCode && len(changes) > 0, func(out io.Writer) error {
+			switch format {
+			case "text":
+				printRuleChangesText(out, changes)
+			case "markdown":
+				printRuleChangesMarkdown(out, changes)
+			case "json":
+				return printRuleChangesJSON(out, changes)
+			}
+			return nil
+		})
+	}
+
+	// Only files that exist in the head revision are affected
+	var files []string
+	if headRev != "" {
+		files, err = repo.FilesAtRevision(headRev)
+	} else {
+		files, err = repo.TrackedFiles()
+	}
+	if err != nil {
+		return err
+	}
+
+	transitions, err := codeowners.DiffOwnership(oldRuleset, newRuleset, files)
+	if err != nil {
+		return err
+	}
+
+	return printDiff(format, exitCode && len(transitions) > 0, func(out io.Writer) error {
+		switch format {
+		case "text":
+			printTransitionsText(out, transitions)
+		case "markdown":
+			printTransitionsMarkdown(out, transitions)
+		case "json":
+			return printTransitionsJSON(out, transitions)
+		}
+		return nil
+	})
+}
+
+// printDiff validates the output format, then writes a diff to stdout with the
+// print function provided. If changed is true, it exits with status 1 after
+// printing.
+func printDiff(format string, changed bool, print func(out io.Writer) error) error {
+	switch format {
+	case "text", "markdown", "json":
+	default:
+		return fmt.Errorf("unknown format %q", format)
+	}
+
+	out := bufio.NewWriter(os.Stdout)
+	if err := print(out); err != nil {
+		return err
+	}
+	if err := out.Flush(); err != nil {
+		return err
+	}
+
+	if changed {
+		os.Exit(1)
+	}
+	return nil
+}
+
+func printTransitionsText(out io.Writer, transitions []codeowners.OwnershipTransition) {
+	for _, t := range transitions {
+		fmt.Fprintf(out, "%s -> %s (%s)\n", formatOwners(t.OldOwners), formatOwners(t.NewOwners), pluralize(len(t.Paths), "file"))
+		for _, path := range t.Paths {
+			fmt.Fprintf(out, "    %s\n", path)
+		}
+	}
+}
+
+// printTransitionsMarkdown prints transitions in a format suitable for
+// commenting on pull requests.
+func printTransitionsMarkdown(out io.Writer, transitions []codeowners.OwnershipTransition) {
+	if len(transitions) == 0 {
+		fmt.Fprintln(out, "This change doesn't affect the ownership of any files.")
+		return
+	}
+
+	fileCount := 0
+	for _, t := range transitions {
+		fileCount += len(t.Paths)
+	}
+	fmt.Fprintf(out, "This change affects the ownership of %s.\n", pluralize(fileCount, "file"))
+
+	for _, t := range transitions {
+		fmt.Fprintf(out, "\n<details>\n<summary>%s → %s (%s)</summary>\n\n",
+			markdownOwners(t.OldOwners), markdownOwners(t.NewOwners), pluralize(len(t.Paths), "file"))
+		for _, path := range t.Paths {
+			fmt.Fprintf(out, "- `%s`\n", path)
+		}
+		fmt.Fprintln(out, "\n</details>")
+	}
+}
+
+type jsonTransition struct {
+	Kind      string   `json:"kind"`
+	OldOwners []string `json:"old_owners"`
+	NewOwners []string `json:"new_owners"`
+	Paths     []string `json:"paths"`
+}
+
+func printTransitionsJSON(out io.Writer, transitions []codeowners.OwnershipTransition) error {
+	result := make([]jsonTransition, 0, len(transitions))
+	for _, t := range transitions {
+		result = append(result, jsonTransition{
+			Kind:      t.Kind,
+			OldOwners: ownerStrings(t.OldOwners),
+			NewOwners: ownerStrings(t.NewOwners),
+			Paths:     t.Paths,
+		})
+	}
+
+	enc := json.NewEncoder(out)
+	enc.SetIndent("", "  ")
+	return enc.Encode(result)
+}
+
+func printRuleChangesText(out io.Writer, changes []codeowners.RuleChange) {
+	for _, c := range changes {
+		switch c.Kind {
+		case codeowners.RuleAdded:
+			fmt.Fprintf(out, "+ %s %s (line %d)\n", c.Pattern, formatOwners(c.NewRule.Owners), c.NewRule.LineNumber)
+		case codeowners.RuleRemoved:
+			fmt.Fprintf(out, "- %s %s (line %d)\n", c.Pattern, formatOwners(c.OldRule.Owners), c.OldRule.LineNumber)
+		case codeowners.RuleMoved:
+			fmt.Fprintf(out, "> %s moved from line %d to line %d\n", c.Pattern, c.OldRule.LineNumber, c.NewRule.LineNumber)
+		case codeowners.RuleSectionChanged:
+			fmt.Fprintf(out, "> %s moved from %s to %s (line %d)\n", c.Pattern, sectionLabel(c.OldRule.Section), sectionLabel(c.NewRule.Section), c.NewRule.LineNumber)
+		case codeowners.RuleOwnersChanged:
+			fmt.Fprintf(out, "~ %s %s -> %s (line %d)\n", c.Pattern, formatOwners(c.OldRule.Owners), formatOwners(c.NewRule.Owners), c.NewRule.LineNumber)
+		}
+	}
+}
+
+func printRuleChangesMarkdown(out io.Writer, changes []codeowners.RuleChange) {
+	if len(changes) == 0 {
+		fmt.Fprintln(out, "This change doesn't affect any CODEOWNERS rules.")
+		return
+	}
+
+	fmt.Fprintf(out, "This change affects %s:\n\n", pluralize(len(changes), "rule"))
+	for _, c := range changes {
+		switch c.Kind {
+		case codeowners.RuleAdded:
+			fmt.Fprintf(out, "- **Added** `%s` owned by %s\n", c.Pattern, markdownOwners(c.NewRule.Owners))
+		case codeowners.RuleRemoved:
+			fmt.Fprintf(out, "- **Removed** `%s` owned by %s\n", c.Pattern, markdownOwners(c.OldRule.Owners))
+		case codeowners.RuleMoved:
+			fmt.Fprintf(out, "- **Moved** `%s` from line %d to line %d\n", c.Pattern, c.OldRule.LineNumber, c.NewRule.LineNumber)
+		case codeowners.RuleSectionChanged:
+			fmt.Fprintf(out, "- **Moved** `%s` from %s to %s\n", c.Pattern, sectionLabel(c.OldRule.Section), sectionLabel(c.NewRule.Section))
+		case codeowners.RuleOwnersChanged:
+			fmt.Fprintf(out, "- **Changed owners** of `%s` from %s to %s\n", c.Pattern, markdownOwners(c.OldRule.Owners), markdownOwners(c.NewRule.Owners))
+		}
+	}
+}
+
+type jsonRuleChange struct {
+	Kind       string   `json:"kind"`
+	Pattern    string   `json:"pattern"`
+	OldLine    int      `json:"old_line,omitempty"`
+	NewLine    int      `json:"new_line,omitempty"`
+	OldSection string   `json:"old_section,omitempty"`
+	NewSection string   `json:"new_section,omitempty"`
+	OldOwners  []string `json:"old_owners,omitempty"`
+	NewOwners  []string `json:"new_owners,omitempty"`
+}
+
+func printRuleChangesJSON(out io.Writer, changes []codeowners.RuleChange) error {
+	result := make([]jsonRuleChange, 0, len(changes))
+	for _, c := range changes {
+		change := jsonRuleChange{Kind: c.Kind, Pattern: c.Pattern}
+		if c.OldRule != nil {
+			change.OldLine = c.OldRule.LineNumber
+			if c.OldRule.Section != nil {
+				change.OldSection = c.OldRule.Section.Name
+			}
+			change.OldOwners = ownerStrings(c.OldRule.Owners)
+		}
+		if c.NewRule != nil {
+			change.NewLine = c.NewRule.LineNumber
+			if c.NewRule.Section != nil {
+				change.NewSection = c.NewRule.Section.Name
+			}
+			change.NewOwners = ownerStrings(c.NewRule.Owners)
+		}
+		result = append(result, change)
+	}
+
+	enc := json.NewEncoder(out)
+	enc.SetIndent("", "  ")
+	return enc.Encode(result)
+}
+
+// sectionLabel returns the section's header, or "(no section)" for rules
+// before the first section.
+func sectionLabel(section *codeowners.Section) string {
+	if section == nil {
+		return "(no section)"
+	}
+	return "[" + section.Name + "]"
+}
+
+// formatOwners returns a space-separated list of owners, or "(unowned)".
+func formatOwners(owners []codeowners.Owner) string {
+	if len(owners) == 0 {
+		return "(unowned)"
+	}
+	return strings.Join(ownerStrings(owners), " ")
+}
+
+// markdownOwners formats owners as inline code, so they don't notify anyone.
+func markdownOwners(owners []codeowners.Owner) string {
+	if len(owners) == 0 {
+		return "_unowned_"
+	}
+	return "`" + strings.Join(ownerStrings(owners), "` `") + "`"
+}
+
+func ownerStrings(owners []codeowners.Owner) []string {
+	strs := make([]string, len(owners))
+	for i, o := range owners {
+		strs[i] = o.String()
+	}
+	return strs
+}
+
+func pluralize(n int, noun string) string {
+	if n == 1 {
+		return fmt.Sprintf("1 %s", noun)
+	}
+	return fmt.Sprintf("%d %ss", n, noun)
+}
@@ -0,0 +1,238 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/hmarr/codeowners"
+	flag "github.com/spf13/pflag"
+	"gopkg.in/yaml.v3"
+)
+
+// runDrift implements the drift subcommand, which reports directories whose
+// owners haven't made any of the recent commits to them.
+func runDrift(args []string) error {
+	var (
+		codeownersPath string
+		authorsPath    string
+		teamsPath      string
+		commitCount    int
+		minCommits     int
+		depth          int
+		format         string
+		helpFlag       bool
+	)
+	flags := flag.NewFlagSet("drift", flag.ContinueOnError)
+	flags.StringVarP(&codeownersPath, "file", "f", "", "CODEOWNERS file path")
+	flags.StringVarP(&authorsPath, "authors", "a", "", "YAML file mapping author emails to owners")
+	flags.StringVarP(&teamsPath, "teams", "t", "", "YAML file mapping teams to their members")
+	flags.IntVarP(&commitCount, "commits", "n", 20, "number of recent commits to each directory to consider")
+	flags.IntVar(&minCommits, "min-commits", 5, "minimum number of recent commits for a directory to be reported")
+	flags.IntVarP(&depth, "depth", "d", 0, "group directories deeper than this with their ancestor (0 for no limit)")
+	flags.StringVar(&format, "format", "text", "output format: text, markdown, or json")
+	flags.BoolVarP(&helpFlag, "help", "h", false, "show this help message")
+	flags.Usage = func() {
+		fmt.Fprintf(os.Stderr, "usage: codeowners drift [options] [<path>...]\n\n")
+		fmt.Fprintf(os.Stderr, "Reports directories where none of the recent commits were made by the owners\n")
+		fmt.Fprintf(os.Stderr, "in CODEOWNERS, which suggests the ownership is out of date. Commits by team\n")
+		fmt.Fprintf(os.Stderr, "members only count for the team if the team is listed in the --teams file.\n\n")
+		flags.PrintDefaults()
+	}
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	if helpFlag {
+		flags.Usage()
+		return nil
+	}
+
+	repo, err := codeowners.FindRepository(".")
+	if err != nil {
+		return err
+	}
+	ruleset, err := loadCodeowners(repo, codeownersPath, "", "", matchOptions{})
+	if err != nil {
+		return err
+	}
+
+	authors := codeowners.AuthorMap{}
+	if authorsPath != "" {
+		authors, err = loadAuthorMap(authorsPath)
+		if err != nil {
+			return err
+		}
+	}
+	var teams map[string][]codeowners.Owner
+	if teamsPath != "" {
+		teams, err = loadTeams(teamsPath)
+		if err != nil {
+			return err
+		}
+	}
+
+	// Drift is detected for the whole repository, so directories grouped by
+	// --depth include all of their files, and then narrowed to the paths
+	prefixes, err := repoRelativePaths(repo, nonEmpty(flags.Args(), "."))
+	if err != nil {
+		return err
+	}
+	files, err := repo.TrackedFiles()
+	if err != nil {
+		return err
+	}
+
+	commits, err := repo.Log(codeowners.LogOptions{})
+	if err != nil {
+		return err
+	}
+
+	drifts, err := codeowners.DetectDrift(ruleset, files, commits, codeowners.DriftOptions{
+		Authors:    authors,
+		Teams:      teams,
+		Commits:    commitCount,
+		MinCommits: minCommits,
+		Depth:      depth,
+	})
+	if err != nil {
+		return err
+	}
+	drifts = driftsUnder(drifts, prefixes)
+
+	return printDiff(format, false, func(out io.Writer) error {
+		switch format {
+		case "text":
+			printDriftText(out, drifts)
+		case "markdown":
+			printDriftMarkdown(out, drifts)
+		case "json":
+			return printDriftJSON(out, drifts)
+		}
+		return nil
+	})
+}
+
+// driftsUnder returns the drifts covering any files inside the prefixes,
+// which are relative to the repository root.
+func driftsUnder(drifts []codeowners.OwnershipDrift, prefixes []string) []codeowners.OwnershipDrift {
+	var filtered []codeowners.OwnershipDrift
+	for _, d := range drifts {
+		if anyPathUnder(d.Paths, prefixes) {
+			filtered = append(filtered, d)
+		}
+	}
+	return filtered
+}
+
+func printDriftText(out io.Writer, drifts []codeowners.OwnershipDrift) {
+	for _, d := range drifts {
+		fmt.Fprintf(out, "%s  %s (%s, owners last committed %s)\n",
+			driftDir(d.Dir), formatOwners(d.Owners), pluralize(d.Commits, "recent commit"), lastOwnerCommit(d.LastOwnerCommit))
+		for _, a := range d.Authors {
+			fmt.Fprintf(out, "    %-40s  %s\n", a.Owner.String(), pluralize(a.Commits, "commit"))
+		}
+	}
+}
+
+// printDriftMarkdown prints a table suitable for sharing as a report.
+func printDriftMarkdown(out io.Writer, drifts []codeowners.OwnershipDrift) {
+	if len(drifts) == 0 {
+		fmt.Fprintln(out, "The owners of every directory have made recent commits to it.")
+		return
+	}
+
+	if len(drifts) == 1 {
+		fmt.Fprintln(out, "The owners of 1 directory haven't made any of the recent commits to it.")
+	} else {
+		fmt.Fprintf(out, "The owners of %d directories haven't made any of the recent commits to them.\n", len(drifts))
+	}
+	fmt.Fprintln(out)
+	fmt.Fprintln(out, "| Directory | Owners | Recent commits | Top authors | Owners last committed |")
+	fmt.Fprintln(out, "| --- | --- | --- | --- | --- |")
+	for _, d := range drifts {
+		var authors []string
+		for i, a := range d.Authors {
+			if i == 3 {
+				break
+			}
+			authors = append(authors, fmt.Sprintf("`%s` (%d)", a.Owner.String(), a.Commits))
+		}
+		fmt.Fprintf(out, "| `%s` | %s | %d | %s | %s |\n",
+			driftDir(d.Dir), markdownOwners(d.Owners), d.Commits, strings.Join(authors, ", "), lastOwnerCommit(d.LastOwnerCommit))
+	}
+}
+
+type jsonDrift struct {
+	Dir             string              `json:"dir"`
+	Owners          []string            `json:"owners"`
+	Commits         int                 `json:"commits"`
+	Authors         []jsonAuthorCommits `json:"authors"`
+	LastOwnerCommit *time.Time          `json:"last_owner_commit"`
+}
+
+type jsonAuthorCommits struct {
+	Owner   string `json:"owner"`
+	Commits int    `json:"commits"`
+}
+
+func printDriftJSON(out io.Writer, drifts []codeowners.OwnershipDrift) error {
+	result := make([]jsonDrift, 0, len(drifts))
+	for _, d := range drifts {
+		drift := jsonDrift{
+			Dir:     driftDir(d.Dir),
+			Owners:  ownerStrings(d.Owners),
+			Commits: d.Commits,
+		}
+		for _, a := range d.Authors {
+			drift.Authors = append(drift.Authors, jsonAuthorCommits{Owner: a.Owner.String(), Commits: a.Commits})
+		}
+		if !d.LastOwnerCommit.IsZero() {
+			drift.LastOwnerCommit = &d.LastOwnerCommit
+		}
+		result = append(result, drift)
+	}
+
+	enc := json.NewEncoder(out)
+	enc.SetIndent("", "  ")
+	return enc.Encode(result)
+}
+
+// driftDir formats a directory relative to the repository root for display.
+func driftDir(dir string) string {
+	return "/" + dir
+}
+
+func lastOwnerCommit(t time.Time) string {
+	if t.IsZero() {
+		return "never"
+	}
+	return t.Format("2006-01-02")
+}
+
+// loadTeams reads a YAML file mapping teams to their members, e.g.
+// `"@org/team": ["@alice", bob@example.com]`.
+func loadTeams(path string) (map[string][]codeowners.Owner, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var raw map[string]ownerList
+	if err := yaml.Unmarshal(data, &raw); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+
+	teams := make(map[string][]codeowners.Owner, len(raw))
+	for team, members := range raw {
+		owner, err := parseOwner(team)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", path, err)
+		}
+		teams[owner.String()] = members
+	}
+	return teams, nil
+}
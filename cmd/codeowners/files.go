@@ -0,0 +1,266 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/hmarr/codeowners"
+)
+
+// walker walks directory trees, optionally skipping files ignored by git.
+type walker struct {
+	// root is the directory that ignore patterns are relative to: the top level
+	// of the git repository, or the current directory outside of one.
+	root string
+	cwd  string
+	// ignore is nil when ignored files shouldn't be skipped.
+	ignore *codeowners.Gitignore
+	// loaded records the directories whose .gitignore files have been read.
+	loaded map[string]bool
+}
+
+// newWalker creates a walker. If useGitignore is true, files ignored by
+// .gitignore files, .git/info/exclude, or the user's global excludes file are
+// skipped.
+func newWalker(useGitignore bool) (*walker, error) {
+	cwd, err := os.Getwd()
+	if err != nil {
+		return nil, err
+	}
+
+	w := &walker{root: cwd, cwd: cwd, loaded: map[string]bool{}}
+	if repo, err := codeowners.FindRepository(cwd); err == nil {
+		w.root = repo.Root
+	}
+
+	if useGitignore {
+		w.ignore, err = codeowners.LoadGitignore(w.root)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return w, nil
+}
+
+// walk calls fn for each file in the directory tree rooted at startPath. Git
+// metadata directories are skipped, including those belonging to nested
+// repositories and submodules.
+func (w *walker) walk(startPath string, fn func(path string) error) error {
+	return filepath.WalkDir(startPath, func(path string, d os.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+
+		if d.IsDir() && d.Name() == ".git" {
+			return filepath.SkipDir
+		}
+
+		ignored, err := w.ignored(path, d.IsDir())
+		if err != nil {
+			return err
+		}
+		if ignored {
+			if d.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+
+		// Only show code owners for files, not directories
+		if !d.IsDir() {
+			return fn(path)
+		}
+		return nil
+	})
+}
+
+// ignored checks whether the path is ignored, loading any .gitignore files that
+// apply to it first. It always returns false if ignored files aren't skipped.
+func (w *walker) ignored(path string, isDir bool) (bool, error) {
+	if w.ignore == nil {
+		return false, nil
+	}
+
+	if !filepath.IsAbs(path) {
+		path = filepath.Join(w.cwd, path)
+	}
+	rel, err := filepath.Rel(w.root, path)
+	if err != nil {
+		return false, err
+	}
+	rel = filepath.ToSlash(rel)
+	if rel == "." || strings.HasPrefix(rel, "../") {
+		// Paths outside the root aren't affected by its ignore files
+		return false, nil
+	}
+
+	dir := pathDir(rel)
+	if err := w.loadGitignores(dir); err != nil {
+		return false, err
+	}
+	if w.ignore.Match(rel, isDir) {
+		return true, nil
+	}
+	if isDir {
+		return false, w.loadGitignores(rel)
+	}
+	return false, nil
+}
+
+// loadGitignores loads the .gitignore files in dir and all of its parents up to
+// the root, unless they've been loaded already. The dir is relative to the root
+// and an empty string refers to the root itself.
+func (w *walker) loadGitignores(dir string) error {
+	if w.loaded[dir] {
+		return nil
+	}
+	if dir != "" {
+		if err := w.loadGitignores(pathDir(dir)); err != nil {
+			return err
+		}
+	}
+	w.loaded[dir] = true
+	return w.ignore.AddFile(filepath.Join(w.root, filepath.FromSlash(dir), ".gitignore"), dir)
+}
+
+// pathDir returns the parent of a slash-separated relative path, using an empty
+// string rather than "." for the root.
+func pathDir(path string) string {
+	if i := strings.LastIndexByte(path, '/'); i >= 0 {
+		return path[:i]
+	}
+	return ""
+}
+
+// gitFiles lists the files tracked by git under the paths provided. If rev
+// isn't empty, the files in that revision's tree are listed rather than those
+// in the index. If untracked is true, files that aren't tracked but also aren't
+// ignored by git are included too. Paths are relative to the current directory.
+func gitFiles(repo *codeowners.Repository, paths []string, rev string, untracked bool) ([]string, error) {
+	cwd, err := os.Getwd()
+	if err != nil {
+		return nil, err
+	}
+
+	// Git paths are relative to the repository root, so convert the path
+	// arguments to match
+	prefixes, err := repoRelativePaths(repo, paths)
+	if err != nil {
+		return nil, err
+	}
+
+	var tracked []string
+	if rev != "" {
+		tracked, err = repo.FilesAtRevision(rev)
+	} else {
+		tracked, err = repo.TrackedFiles()
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var files []string
+	for _, file := range tracked {
+		if !hasPathPrefix(file, prefixes) {
+			continue
+		}
+		rel, err := filepath.Rel(cwd, filepath.Join(repo.Root, filepath.FromSlash(file)))
+		if err != nil {
+			return nil, err
+		}
+		files = append(files, rel)
+	}
+
+	if untracked {
+		others, err := untrackedFiles(paths, files)
+		if err != nil {
+			return nil, err
+		}
+		files = append(files, others...)
+		sort.Strings(files)
+	}
+	return files, nil
+}
+
+// repoRelativePath converts a path relative to the current directory into a
+// slash-separated path relative to the root of the repository.
+func repoRelativePath(repo *codeowners.Repository, path string) (string, error) {
+	absPath, err := filepath.Abs(path)
+	if err != nil {
+		return "", err
+	}
+	rel, err := filepath.Rel(repo.Root, absPath)
+	if err != nil {
+		return "", err
+	}
+	return filepath.ToSlash(rel), nil
+}
+
+// repoRelativePaths converts each path with repoRelativePath.
+func repoRelativePaths(repo *codeowners.Repository, paths []string) ([]string, error) {
+	relPaths := make([]string, 0, len(paths))
+	for _, path := range paths {
+		relPath, err := repoRelativePath(repo, path)
+		if err != nil {
+			return nil, err
+		}
+		relPaths = append(relPaths, relPath)
+	}
+	return relPaths, nil
+}
+
+// hasPathPrefix checks whether the path is, or is inside, any of the prefixes.
+func hasPathPrefix(path string, prefixes []string) bool {
+	for _, prefix := range prefixes {
+		if prefix == "." || path == prefix || strings.HasPrefix(path, prefix+"/") {
+			return true
+		}
+	}
+	return false
+}
+
+// anyPathUnder checks whether any of the paths is, or is inside, any of the
+// prefixes.
+func anyPathUnder(paths []string, prefixes []string) bool {
+	for _, path := range paths {
+		if hasPathPrefix(path, prefixes) {
+			return true
+		}
+	}
+	return false
+}
+
+// untrackedFiles lists files under the paths provided that aren't tracked but
+// also aren't ignored, by walking the file system and applying the same
+// exclude patterns as git. Paths are relative to the current directory.
+func untrackedFiles(paths []string, tracked []string) ([]string, error) {
+	w, err := newWalker(true)
+	if err != nil {
+		return nil, err
+	}
+
+	isTracked := make(map[string]bool, len(tracked))
+	for _, path := range tracked {
+		isTracked[path] = true
+	}
+
+	var files []string
+	for _, startPath := range paths {
+		if _, err := os.Lstat(startPath); os.IsNotExist(err) {
+			continue
+		}
+		err := w.walk(startPath, func(path string) error {
+			path = filepath.Clean(path)
+			if !isTracked[path] {
+				files = append(files, path)
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+	return files, nil
+}
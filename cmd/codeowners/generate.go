@@ -0,0 +1,170 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/hmarr/codeowners"
+	flag "github.com/spf13/pflag"
+	"gopkg.in/yaml.v3"
+)
+
+const defaultGeneratedHeader = "This file is generated by `codeowners generate`. Do not edit it by hand."
+
+// runGenerate implements the generate subcommand, which builds a CODEOWNERS
+// file from a mapping of directories to owners.
+func runGenerate(args []string) error {
+	var (
+		metadataName string
+		outputPath   string
+		header       string
+		helpFlag     bool
+	)
+	flags := flag.NewFlagSet("generate", flag.ContinueOnError)
+	flags.StringVarP(&metadataName, "metadata", "m", "", "read owners from per-directory metadata files with this name")
+	flags.StringVar(&outputPath, "output", "", "write to this file rather than standard output")
+	flags.StringVar(&header, "header", defaultGeneratedHeader, "comment to add to the top of the file (empty for none)")
+	flags.BoolVarP(&helpFlag, "help", "h", false, "show this help message")
+	flags.Usage = func() {
+		fmt.Fprintf(os.Stderr, "usage: codeowners generate [options] <mapping.yml>\n")
+		fmt.Fprintf(os.Stderr, "       codeowners generate [options] --metadata <filename>\n\n")
+		fmt.Fprintf(os.Stderr, "Generates a CODEOWNERS file from a YAML file that maps directories to owners,\n")
+		fmt.Fprintf(os.Stderr, "or from metadata files spread across the repository that each list the owners\n")
+		fmt.Fprintf(os.Stderr, "of their own directory under an \"owners\" key. An empty list of owners leaves a\n")
+		fmt.Fprintf(os.Stderr, "directory unowned.\n\n")
+		flags.PrintDefaults()
+	}
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	if helpFlag {
+		flags.Usage()
+		return nil
+	}
+
+	var (
+		mapping []codeowners.DirectoryOwners
+		err     error
+	)
+	switch {
+	case metadataName != "" && flags.NArg() == 0:
+		mapping, err = loadMetadataFiles(metadataName)
+	case metadataName == "" && flags.NArg() == 1:
+		mapping, err = loadMappingFile(flags.Arg(0))
+	default:
+		flags.Usage()
+		return fmt.Errorf("expected either a mapping file or --metadata")
+	}
+	if err != nil {
+		return err
+	}
+
+	ruleset, err := codeowners.Generate(mapping)
+	if err != nil {
+		return err
+	}
+
+	var buf bytes.Buffer
+	if header != "" {
+		for _, line := range strings.Split(header, "\n") {
+			fmt.Fprintf(&buf, "# %s\n", line)
+		}
+		buf.WriteString("\n")
+	}
+	if err := codeowners.WriteFile(&buf, ruleset); err != nil {
+		return err
+	}
+
+	if outputPath != "" {
+		return os.WriteFile(outputPath, buf.Bytes(), 0o644)
+	}
+	_, err = io.Copy(os.Stdout, &buf)
+	return err
+}
+
+// ownerList is a list of owners in YAML, which may also be written as a single
+// string of space-separated owners like in a CODEOWNERS file.
+type ownerList []codeowners.Owner
+
+func (l *ownerList) UnmarshalYAML(node *yaml.Node) error {
+	var values []string
+	if node.Kind == yaml.ScalarNode && node.Tag != "!!null" {
+		values = strings.Fields(node.Value)
+	} else if err := node.Decode(&values); err != nil {
+		return err
+	}
+
+	*l = nil
+	for _, value := range values {
+		owner, err := parseOwner(value)
+		if err != nil {
+			return fmt.Errorf("line %d: %w", node.Line, err)
+		}
+		*l = append(*l, owner)
+	}
+	return nil
+}
+
+// loadMappingFile reads a YAML file mapping directories to owners, e.g.
+// `services/api: ["@org/platform"]`.
+func loadMappingFile(path string) ([]codeowners.DirectoryOwners, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var raw map[string]ownerList
+	if err := yaml.Unmarshal(data, &raw); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+
+	mapping := make([]codeowners.DirectoryOwners, 0, len(raw))
+	for dir, owners := range raw {
+		mapping = append(mapping, codeowners.DirectoryOwners{Dir: dir, Owners: owners})
+	}
+	return mapping, nil
+}
+
+// loadMetadataFiles finds the metadata files with the name provided throughout
+// the repository (skipping ignored files), and reads the owners of each
+// directory from them.
+func loadMetadataFiles(name string) ([]codeowners.DirectoryOwners, error) {
+	w, err := newWalker(true)
+	if err != nil {
+		return nil, err
+	}
+
+	var mapping []codeowners.DirectoryOwners
+	err = w.walk(w.root, func(path string) error {
+		if filepath.Base(path) != name {
+			return nil
+		}
+
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		var metadata struct {
+			Owners ownerList `yaml:"owners"`
+		}
+		if err := yaml.Unmarshal(data, &metadata); err != nil {
+			return fmt.Errorf("%s: %w", path, err)
+		}
+
+		dir, err := filepath.Rel(w.root, filepath.Dir(path))
+		if err != nil {
+			return err
+		}
+		mapping = append(mapping, codeowners.DirectoryOwners{
+			Dir:    filepath.ToSlash(dir),
+			Owners: metadata.Owners,
+		})
+		return nil
+	})
+	return mapping, err
+}
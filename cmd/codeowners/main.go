@@ -2,6 +2,7 @@ package main
 
 import (
 	"bufio"
+	"errors"
 	"fmt"
 	"io"
 	"os"
@@ -12,20 +13,75 @@ import (
 	flag "github.com/spf13/pflag"
 )
 
+// subcommands maps the names of subcommands to their implementations, which
+// receive the arguments that follow the subcommand name.
+var subcommands = map[string]func(args []string) error{
+	"blame":       runBlame,
+	"compile":     runCompile,
+	"convert":     runConvert,
+	"diff":        runDiff,
+	"drift":       runDrift,
+	"from-owners": runFromOwners,
+	"generate":    runGenerate,
+	"optimize":    runOptimize,
+	"suggest":     runSuggest,
+	"to-owners":   runToOwners,
+	"validate":    runValidate,
+}
+
 func main() {
+	if len(os.Args) > 1 {
+		if subcommand, ok := subcommands[os.Args[1]]; ok {
+			if err := subcommand(os.Args[2:]); err != nil {
+				fmt.Fprintf(os.Stderr, "error: %v\n", err)
+				os.Exit(1)
+			}
+			return
+		}
+	}
+
 	var (
 		ownerFilters   []string
 		showUnowned    bool
 		codeownersPath string
+		walk           bool
+		untracked      bool
+		useGitignore   bool
+		rev            string
+		ownersFiles    bool
+		nested         bool
+		dialectName    string
+		matchOpts      matchOptions
 		helpFlag       bool
 	)
 	flag.StringSliceVarP(&ownerFilters, "owner", "o", nil, "filter results by owner")
 	flag.BoolVarP(&showUnowned, "unowned", "u", false, "only show unowned files (can be combined with -o)")
 	flag.StringVarP(&codeownersPath, "file", "f", "", "CODEOWNERS file path")
+	flag.BoolVarP(&walk, "walk", "w", false, "walk the file system rather than listing files tracked by git")
+	flag.BoolVar(&untracked, "untracked", false, "include untracked files that aren't ignored by git")
+	flag.BoolVarP(&useGitignore, "gitignore", "g", false, "skip files ignored by .gitignore when walking the file system")
+	flag.StringVarP(&rev, "rev", "r", "", "show ownership as of a git revision (e.g. a commit, branch, or tag)")
+	flag.BoolVar(&nested, "nested", false, "merge the CODEOWNERS files in subdirectories into the root file")
+	flag.BoolVar(&ownersFiles, "owners-files", false, "use Chromium-style OWNERS files rather than a CODEOWNERS file")
+	flag.BoolVarP(&matchOpts.ignoreCase, "ignore-case", "i", false, "match paths and --owner filters regardless of case")
+	flag.BoolVar(&matchOpts.extendedPatterns, "extended-patterns", false, "support character classes like [a-z] and brace expansion like *.{js,ts} in patterns")
+	flag.BoolVar(&matchOpts.normalizeUnicode, "normalize-unicode", false, "match paths regardless of Unicode normalization form (NFC or NFD)")
+	flag.StringVar(&dialectName, "dialect", "", "CODEOWNERS dialect: github, gitlab, or bitbucket (detected by default)")
 	flag.BoolVarP(&helpFlag, "help", "h", false, "show this help message")
 
 	flag.Usage = func() {
 		fmt.Fprintf(os.Stderr, "usage: codeowners <path>...\n")
+		fmt.Fprintf(os.Stderr, "       codeowners blame <path>...\n")
+		fmt.Fprintf(os.Stderr, "       codeowners diff <base-rev> [<head-rev>]\n")
+		fmt.Fprintf(os.Stderr, "       codeowners suggest [<path>...]\n")
+		fmt.Fprintf(os.Stderr, "       codeowners generate <mapping.yml>\n")
+		fmt.Fprintf(os.Stderr, "       codeowners drift [<path>...]\n")
+		fmt.Fprintf(os.Stderr, "       codeowners from-owners\n")
+		fmt.Fprintf(os.Stderr, "       codeowners to-owners\n")
+		fmt.Fprintf(os.Stderr, "       codeowners compile\n")
+		fmt.Fprintf(os.Stderr, "       codeowners convert --to <dialect>\n")
+		fmt.Fprintf(os.Stderr, "       codeowners validate\n")
+		fmt.Fprintf(os.Stderr, "       codeowners optimize\n")
 		flag.PrintDefaults()
 	}
 	flag.Parse()
@@ -35,7 +91,74 @@ func main() {
 		os.Exit(0)
 	}
 
-	ruleset, err := loadCodeowners(codeownersPath)
+	if walk && untracked {
+		fmt.Fprintln(os.Stderr, "error: --untracked can't be combined with --walk")
+		os.Exit(1)
+	}
+
+	if rev != "" && (walk || untracked) {
+		fmt.Fprintln(os.Stderr, "error: --rev can't be combined with --walk or --untracked")
+		os.Exit(1)
+	}
+
+	// Outside a git repository the file system is walked instead, but a
+	// repository that can't be read is an error
+	repo, repoErr := codeowners.FindRepository(".")
+	if repoErr != nil && !errors.Is(repoErr, codeowners.ErrNotRepository) {
+		fmt.Fprintf(os.Stderr, "error: %v\n", repoErr)
+		os.Exit(1)
+	}
+	if rev != "" && repoErr != nil {
+		fmt.Fprintf(os.Stderr, "error: --rev requires a git repository: %v\n", repoErr)
+		os.Exit(1)
+	}
+
+	if ownersFiles && (rev != "" || codeownersPath != "") {
+		fmt.Fprintln(os.Stderr, "error: --owners-files can't be combined with --rev or --file")
+		os.Exit(1)
+	}
+
+	if nested && (rev != "" || codeownersPath != "" || ownersFiles) {
+		fmt.Fprintln(os.Stderr, "error: --nested can't be combined with --rev, --file, or --owners-files")
+		os.Exit(1)
+	}
+	if nested && repoErr != nil {
+		fmt.Fprintf(os.Stderr, "error: --nested requires a git repository: %v\n", repoErr)
+		os.Exit(1)
+	}
+
+	var dialect codeowners.Dialect
+	if dialectName != "" {
+		var err error
+		if dialect, err = codeowners.ParseDialect(dialectName); err != nil {
+			fmt.Fprintf(os.Stderr, "error: %v\n", err)
+			os.Exit(1)
+		}
+	}
+
+	var (
+		owners ownersFunc
+		err    error
+	)
+	if nested {
+		var rulesets []codeowners.ScopedRuleset
+		rulesets, err = loadNestedRulesets(repo, "", dialect, matchOpts)
+		if err == nil {
+			var ruleset codeowners.Ruleset
+			ruleset, err = codeowners.MergeRulesets(rulesets)
+			owners = rulesetOwners(ruleset)
+		}
+	} else if ownersFiles {
+		root := "."
+		if repoErr == nil {
+			root = repo.Root
+		}
+		owners, err = loadOwnersTree(root)
+	} else {
+		var ruleset codeowners.Ruleset
+		ruleset, err = loadCodeowners(repo, codeownersPath, rev, dialect, matchOpts)
+		owners = rulesetOwners(ruleset)
+	}
 	if err != nil {
 		fmt.Fprintln(os.Stderr, err)
 		os.Exit(1)
@@ -54,26 +177,41 @@ func main() {
 	out := bufio.NewWriter(os.Stdout)
 	defer out.Flush()
 
+	// Inside a git repository, list the files git knows about so build artefacts,
+	// dependencies, and other ignored files aren't reported
+	if !walk && repoErr == nil {
+		files, err := gitFiles(repo, paths, rev, untracked)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "error: %v\n", err)
+			os.Exit(1)
+		}
+		for _, path := range files {
+			if err := printFileOwners(out, owners, path, ownerFilters, showUnowned, matchOpts.ignoreCase); err != nil {
+				fmt.Fprintf(os.Stderr, "error: %v\n", err)
+				os.Exit(1)
+			}
+		}
+		return
+	}
+
+	w, err := newWalker(useGitignore)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "error: %v\n", err)
+		os.Exit(1)
+	}
+
 	for _, startPath := range paths {
-		// godirwalk only accepts directories, so we need to handle files separately
+		// The walk only descends into directories, so we need to handle files separately
 		if !isDir(startPath) {
-			if err := printFileOwners(out, ruleset, startPath, ownerFilters, showUnowned); err != nil {
+			if err := printFileOwners(out, owners, startPath, ownerFilters, showUnowned, matchOpts.ignoreCase); err != nil {
 				fmt.Fprintf(os.Stderr, "error: %v", err)
 				os.Exit(1)
 			}
 			continue
 		}
 
-		err = filepath.WalkDir(startPath, func(path string, d os.DirEntry, err error) error {
-			if path == ".git" {
-				return filepath.SkipDir
-			}
-
-			// Only show code owners for files, not directories
-			if !d.IsDir() {
-				return printFileOwners(out, ruleset, path, ownerFilters, showUnowned)
-			}
-			return nil
+		err = w.walk(startPath, func(path string) error {
+			return printFileOwners(out, owners, path, ownerFilters, showUnowned, matchOpts.ignoreCase)
 		})
 
 		if err != nil {
@@ -83,13 +221,68 @@ func main() {
 	}
 }
 
-func printFileOwners(out io.Writer, ruleset codeowners.Ruleset, path string, ownerFilters []string, showUnowned bool) error {
-	rule, err := ruleset.Match(path)
+// ownersFunc returns the owners of a path, or nil if it's unowned.
+type ownersFunc func(path string) ([]codeowners.Owner, error)
+
+// rulesetOwners finds owners using a CODEOWNERS ruleset. If the ruleset has
+// GitLab sections, the owners of the matching rule in each section are combined.
+func rulesetOwners(ruleset codeowners.Ruleset) ownersFunc {
+	return func(path string) ([]codeowners.Owner, error) {
+		rules, err := ruleset.MatchSections(path)
+		if err != nil {
+			return nil, err
+		}
+
+		var owners []codeowners.Owner
+		seen := map[string]bool{}
+		for _, rule := range rules {
+			for _, o := range rule.Owners {
+				if !seen[o.String()] {
+					seen[o.String()] = true
+					owners = append(owners, o)
+				}
+			}
+		}
+		return owners, nil
+	}
+}
+
+// loadOwnersTree finds owners using the OWNERS files under root. Files that
+// anyone may approve changes to are treated as unowned.
+func loadOwnersTree(root string) (ownersFunc, error) {
+	tree, err := codeowners.LoadOwnersTree(root)
+	if err != nil {
+		return nil, err
+	}
+	absRoot, err := filepath.Abs(root)
+	if err != nil {
+		return nil, err
+	}
+
+	return func(path string) ([]codeowners.Owner, error) {
+		absPath, err := filepath.Abs(path)
+		if err != nil {
+			return nil, err
+		}
+		rel, err := filepath.Rel(absRoot, absPath)
+		if err != nil {
+			return nil, err
+		}
+		m, err := tree.Match(rel)
+		if m == nil || m.Anyone || err != nil {
+			return nil, err
+		}
+		return m.Owners, nil
+	}, nil
+}
+
+func printFileOwners(out io.Writer, ownersOf ownersFunc, path string, ownerFilters []string, showUnowned, ignoreCase bool) error {
+	owners, err := ownersOf(path)
 	if err != nil {
 		return err
 	}
 	// If we didn't get a match, the file is unowned
-	if rule == nil || rule.Owners == nil {
+	if len(owners) == 0 {
 		// Unless explicitly requested, don't show unowned files if we're filtering by owner
 		if len(ownerFilters) == 0 || showUnowned {
 			fmt.Fprintf(out, "%-70s  (unowned)\n", path)
@@ -98,12 +291,12 @@ func printFileOwners(out io.Writer, ruleset codeowners.Ruleset, path string, own
 	}
 
 	// Figure out which of the owners we need to show according to the --owner filters
-	ownersToShow := make([]string, 0, len(rule.Owners))
-	for _, o := range rule.Owners {
+	ownersToShow := make([]string, 0, len(owners))
+	for _, o := range owners {
 		// If there are no filters, show all owners
 		filterMatch := len(ownerFilters) == 0 && !showUnowned
 		for _, filter := range ownerFilters {
-			if filter == o.Value {
+			if filter == o.Value || (ignoreCase && strings.EqualFold(filter, o.Value)) {
 				filterMatch = true
 			}
 		}
@@ -119,11 +312,54 @@ func printFileOwners(out io.Writer, ruleset codeowners.Ruleset, path string, own
 	return nil
 }
 
-func loadCodeowners(path string) (codeowners.Ruleset, error) {
-	if path == "" {
-		return codeowners.LoadFileFromStandardLocation()
+// matchOptions are the command line flags that change how patterns match
+// paths.
+type matchOptions struct {
+	ignoreCase       bool
+	extendedPatterns bool
+	normalizeUnicode bool
+}
+
+// loadCodeowners loads the CODEOWNERS file at path, or at a standard location
+// if path is empty, optionally as of a git revision. If dialect is empty, it's
+// detected from the file's location. Like GitHub, invalid lines are skipped
+// with a warning rather than failing.
+func loadCodeowners(repo *codeowners.Repository, path, rev string, dialect codeowners.Dialect, matchOpts matchOptions) (codeowners.Ruleset, error) {
+	var (
+		diagnostics []codeowners.Diagnostic
+		ruleset     codeowners.Ruleset
+		err         error
+	)
+	options := []codeowners.ParseOption{
+		codeowners.WithDialect(dialect),
+		codeowners.WithSkipInvalidLines(&diagnostics),
+		codeowners.WithIgnoreCase(matchOpts.ignoreCase),
+		codeowners.WithUnicodeNormalization(matchOpts.normalizeUnicode),
+	}
+	// Some dialects support extended patterns by default, so the flag can only
+	// turn them on
+	if matchOpts.extendedPatterns {
+		options = append(options, codeowners.WithExtendedPatterns(true))
+	}
+	switch {
+	case rev != "" && path == "":
+		ruleset, err = repo.LoadFileFromStandardLocationAtRevision(rev, options...)
+	case rev != "":
+		var repoPath string
+		if repoPath, err = repoRelativePath(repo, path); err != nil {
+			return nil, err
+		}
+		ruleset, err = repo.LoadFileAtRevision(rev, repoPath, options...)
+	case path == "":
+		ruleset, err = codeowners.LoadFileFromStandardLocation(options...)
+	default:
+		ruleset, err = codeowners.LoadFile(path, options...)
+	}
+
+	for _, d := range diagnostics {
+		fmt.Fprintf(os.Stderr, "warning: skipping %s\n", d)
 	}
-	return codeowners.LoadFile(path)
+	return ruleset, err
 }
 
 // isDir checks if there's a directory at the path specified.
@@ -0,0 +1,137 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/hmarr/codeowners"
+	flag "github.com/spf13/pflag"
+)
+
+// runOptimize implements the optimize subcommand, which shortens a CODEOWNERS
+// file without changing the owners of any path.
+func runOptimize(args []string) error {
+	var (
+		codeownersPath string
+		outputPath     string
+		noMerge        bool
+		helpFlag       bool
+	)
+	flags := flag.NewFlagSet("optimize", flag.ContinueOnError)
+	flags.StringVarP(&codeownersPath, "file", "f", "", "CODEOWNERS file path")
+	flags.StringVar(&outputPath, "output", "", "write to this file rather than standard output")
+	flags.BoolVar(&noMerge, "no-merge", false, "don't merge rules into directory rules, which relies on the files tracked by git")
+	flags.BoolVarP(&helpFlag, "help", "h", false, "show this help message")
+	flags.Usage = func() {
+		fmt.Fprintf(os.Stderr, "usage: codeowners optimize [options]\n\n")
+		fmt.Fprintf(os.Stderr, "Shortens a CODEOWNERS file by removing rules that are always overridden or\n")
+		fmt.Fprintf(os.Stderr, "that give paths the owners they'd have anyway, which holds for every possible\n")
+		fmt.Fprintf(os.Stderr, "path. Rules with the same owners in a directory are also merged into a rule\n")
+		fmt.Fprintf(os.Stderr, "for the directory if none of the files tracked by git change owners. Each\n")
+		fmt.Fprintf(os.Stderr, "change is described on standard error. Comments, blank lines, and lines that\n")
+		fmt.Fprintf(os.Stderr, "can't be parsed are kept.\n\n")
+		flags.PrintDefaults()
+	}
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	if helpFlag {
+		flags.Usage()
+		return nil
+	}
+	if flags.NArg() > 0 {
+		flags.Usage()
+		return fmt.Errorf("unexpected arguments")
+	}
+
+	// The file is edited in place rather than written from the ruleset, so it
+	// needs to be found up front
+	repo, _ := codeowners.FindRepository(".")
+	if codeownersPath == "" {
+		if repo == nil {
+			return fmt.Errorf("finding the CODEOWNERS file requires a git repository, use --file to specify it")
+		}
+		path, err := repo.FindFileAtStandardLocation("")
+		if err != nil {
+			return err
+		}
+		if path == "" {
+			return fmt.Errorf("could not find CODEOWNERS file at any of the standard locations")
+		}
+		codeownersPath = filepath.Join(repo.Root, path)
+	}
+	contents, err := os.ReadFile(codeownersPath)
+	if err != nil {
+		return err
+	}
+	ruleset, err := loadCodeowners(repo, codeownersPath, "", "", matchOptions{})
+	if err != nil {
+		return err
+	}
+
+	var files []string
+	if !noMerge {
+		if repo == nil {
+			return fmt.Errorf("merging rules requires a git repository, use --no-merge to skip it")
+		}
+		if files, err = repo.TrackedFiles(); err != nil {
+			return err
+		}
+	}
+
+	optimized, messages, err := ruleset.Optimize(files)
+	if err != nil {
+		return err
+	}
+	for _, msg := range messages {
+		fmt.Fprintln(os.Stderr, msg)
+	}
+
+	output := rewriteRules(contents, ruleset, optimized)
+	if outputPath != "" {
+		return os.WriteFile(outputPath, output, 0o644)
+	}
+	_, err = os.Stdout.Write(output)
+	return err
+}
+
+// rewriteRules edits the lines of a CODEOWNERS file that hold the original
+// rules to match the optimized ones, which keep the line numbers of the rules
+// they come from. Comments, blank lines, section headers, and lines that
+// couldn't be parsed are left as they are.
+func rewriteRules(contents []byte, original, optimized codeowners.Ruleset) []byte {
+	optimizedRules := map[int]codeowners.Rule{}
+	for _, rule := range optimized {
+		optimizedRules[rule.LineNumber] = rule
+	}
+	originalRules := map[int]codeowners.Rule{}
+	for _, rule := range original {
+		originalRules[rule.LineNumber] = rule
+	}
+
+	var buf bytes.Buffer
+	lines := strings.SplitAfter(string(contents), "\n")
+	for i, line := range lines {
+		lineNumber := i + 1
+		rule, isRule := originalRules[lineNumber]
+		if !isRule {
+			buf.WriteString(line)
+			continue
+		}
+		newRule, kept := optimizedRules[lineNumber]
+		switch {
+		case !kept:
+		case newRule.RawPattern() == rule.RawPattern():
+			buf.WriteString(line)
+		default:
+			// Merged rules replace the line of the last rule merged into them
+			buf.WriteString(newRule.String())
+			buf.WriteString(line[len(strings.TrimRight(line, "\r\n")):])
+		}
+	}
+	return buf.Bytes()
+}
@@ -0,0 +1,149 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/hmarr/codeowners"
+	flag "github.com/spf13/pflag"
+)
+
+// runFromOwners implements the from-owners subcommand, which converts the
+// Chromium-style OWNERS files in a repository into a CODEOWNERS file.
+func runFromOwners(args []string) error {
+	var (
+		outputPath string
+		helpFlag   bool
+	)
+	flags := flag.NewFlagSet("from-owners", flag.ContinueOnError)
+	flags.StringVar(&outputPath, "output", "", "write to this file rather than standard output")
+	flags.BoolVarP(&helpFlag, "help", "h", false, "show this help message")
+	flags.Usage = func() {
+		fmt.Fprintf(os.Stderr, "usage: codeowners from-owners [options]\n\n")
+		fmt.Fprintf(os.Stderr, "Converts the Chromium-style OWNERS files in the repository into a CODEOWNERS\n")
+		fmt.Fprintf(os.Stderr, "file. Anything that can't be converted exactly is reported as a warning.\n\n")
+		flags.PrintDefaults()
+	}
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	if helpFlag {
+		flags.Usage()
+		return nil
+	}
+
+	root := "."
+	if repo, err := codeowners.FindRepository("."); err == nil {
+		root = repo.Root
+	}
+	tree, err := codeowners.LoadOwnersTree(root)
+	if err != nil {
+		return err
+	}
+	ruleset, warnings, err := tree.Ruleset()
+	if err != nil {
+		return err
+	}
+	printWarnings(warnings)
+
+	var buf bytes.Buffer
+	if err := codeowners.WriteFile(&buf, ruleset); err != nil {
+		return err
+	}
+	if outputPath != "" {
+		return os.WriteFile(outputPath, buf.Bytes(), 0o644)
+	}
+	_, err = buf.WriteTo(os.Stdout)
+	return err
+}
+
+// runToOwners implements the to-owners subcommand, which converts a CODEOWNERS
+// file into Chromium-style OWNERS files.
+func runToOwners(args []string) error {
+	var (
+		codeownersPath string
+		write          bool
+		helpFlag       bool
+	)
+	flags := flag.NewFlagSet("to-owners", flag.ContinueOnError)
+	flags.StringVarP(&codeownersPath, "file", "f", "", "CODEOWNERS file path")
+	flags.BoolVar(&write, "write", false, "write the OWNERS files into the repository rather than printing them")
+	flags.BoolVarP(&helpFlag, "help", "h", false, "show this help message")
+	flags.Usage = func() {
+		fmt.Fprintf(os.Stderr, "usage: codeowners to-owners [options]\n\n")
+		fmt.Fprintf(os.Stderr, "Converts a CODEOWNERS file into Chromium-style OWNERS files, one per directory.\n")
+		fmt.Fprintf(os.Stderr, "Rules that can't be expressed by OWNERS files are reported as warnings. With\n")
+		fmt.Fprintf(os.Stderr, "--write, existing OWNERS files are overwritten.\n\n")
+		flags.PrintDefaults()
+	}
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	if helpFlag {
+		flags.Usage()
+		return nil
+	}
+
+	root := "."
+	repo, err := codeowners.FindRepository(".")
+	if err == nil {
+		root = repo.Root
+	}
+	ruleset, err := loadCodeowners(repo, codeownersPath, "", "", matchOptions{})
+	if err != nil {
+		return err
+	}
+	tree, warnings, err := codeowners.OwnersTreeFromRuleset(ruleset)
+	if err != nil {
+		return err
+	}
+	printWarnings(warnings)
+
+	out := bufio.NewWriter(os.Stdout)
+	defer out.Flush()
+	var files []*codeowners.OwnersFile
+	for _, f := range tree.Files() {
+		// Rules like "/src/BUILD" also give an OWNERS file to a directory of
+		// that name, which is skipped if it's a file
+		if info, err := os.Stat(filepath.Join(root, filepath.FromSlash(f.Dir()))); err == nil && !info.IsDir() {
+			continue
+		}
+		files = append(files, f)
+	}
+
+	for i, f := range files {
+		var buf bytes.Buffer
+		if err := codeowners.WriteOwnersFile(&buf, f); err != nil {
+			return err
+		}
+
+		if !write {
+			if i > 0 {
+				fmt.Fprintln(out)
+			}
+			fmt.Fprintf(out, "==> %s <==\n%s", f.Path, buf.String())
+			continue
+		}
+
+		path := filepath.Join(root, filepath.FromSlash(f.Path))
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			return err
+		}
+		if err := os.WriteFile(path, buf.Bytes(), 0o644); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// printWarnings prints conversion warnings to stderr.
+func printWarnings(warnings []string) {
+	for _, warning := range warnings {
+		fmt.Fprintf(os.Stderr, "warning: %s\n", warning)
+	}
+}
@@ -0,0 +1,205 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/hmarr/codeowners"
+	flag "github.com/spf13/pflag"
+	"gopkg.in/yaml.v3"
+)
+
+// runSuggest implements the suggest subcommand, which proposes owners for
+// unowned files based on git history.
+func runSuggest(args []string) error {
+	var (
+		codeownersPath string
+		authorsPath    string
+		halfLifeDays   int
+		maxOwners      int
+		rules          bool
+		helpFlag       bool
+	)
+	flags := flag.NewFlagSet("suggest", flag.ContinueOnError)
+	flags.StringVarP(&codeownersPath, "file", "f", "", "CODEOWNERS file path")
+	flags.StringVarP(&authorsPath, "authors", "a", "", "YAML file mapping author emails to owners")
+	flags.IntVar(&halfLifeDays, "half-life", 180, "age in days at which a commit counts half as much as a new one")
+	flags.IntVarP(&maxOwners, "max-owners", "n", 1, "maximum number of owners per suggested rule")
+	flags.BoolVar(&rules, "rules", false, "print CODEOWNERS rules for unowned directories")
+	flags.BoolVarP(&helpFlag, "help", "h", false, "show this help message")
+	flags.Usage = func() {
+		fmt.Fprintf(os.Stderr, "usage: codeowners suggest [options] [<path>...]\n\n")
+		fmt.Fprintf(os.Stderr, "Suggests owners for unowned files based on who has committed to them, with\n")
+		fmt.Fprintf(os.Stderr, "recent commits counting for more. With --rules, unowned files are grouped\n")
+		fmt.Fprintf(os.Stderr, "into directories and printed as rules that can be added to CODEOWNERS.\n\n")
+		flags.PrintDefaults()
+	}
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	if helpFlag {
+		flags.Usage()
+		return nil
+	}
+
+	repo, err := codeowners.FindRepository(".")
+	if err != nil {
+		return err
+	}
+	ruleset, err := loadCodeowners(repo, codeownersPath, "", "", matchOptions{})
+	if err != nil {
+		return err
+	}
+
+	authors := codeowners.AuthorMap{}
+	if authorsPath != "" {
+		authors, err = loadAuthorMap(authorsPath)
+		if err != nil {
+			return err
+		}
+	}
+
+	// Suggestions are made for the whole repository, so a directory is only
+	// treated as unowned if none of its files are owned, and then narrowed to
+	// the paths
+	prefixes, err := repoRelativePaths(repo, nonEmpty(flags.Args(), "."))
+	if err != nil {
+		return err
+	}
+	files, err := repo.TrackedFiles()
+	if err != nil {
+		return err
+	}
+
+	commits, err := repo.Log(codeowners.LogOptions{})
+	if err != nil {
+		return err
+	}
+
+	suggestions, err := codeowners.SuggestOwners(ruleset, files, commits, codeowners.SuggestOptions{
+		Authors:     authors,
+		HalfLife:    time.Duration(halfLifeDays) * 24 * time.Hour,
+		Directories: rules,
+	})
+	if err != nil {
+		return err
+	}
+	suggestions = suggestionsUnder(suggestions, prefixes)
+
+	out := bufio.NewWriter(os.Stdout)
+	defer out.Flush()
+	if rules {
+		printSuggestedRules(out, suggestions, maxOwners)
+	} else {
+		printSuggestions(out, suggestions)
+	}
+	return nil
+}
+
+// suggestionsUnder returns the suggestions covering any files inside the
+// prefixes, which are relative to the repository root.
+func suggestionsUnder(suggestions []codeowners.OwnerSuggestion, prefixes []string) []codeowners.OwnerSuggestion {
+	var filtered []codeowners.OwnerSuggestion
+	for _, s := range suggestions {
+		if anyPathUnder(s.Paths, prefixes) {
+			filtered = append(filtered, s)
+		}
+	}
+	return filtered
+}
+
+func printSuggestions(out io.Writer, suggestions []codeowners.OwnerSuggestion) {
+	for _, s := range suggestions {
+		if s.Pattern == "" {
+			fmt.Fprintln(out, s.Paths[0])
+		} else {
+			fmt.Fprintln(out, strings.TrimPrefix(s.Pattern, "/"))
+		}
+		if len(s.Candidates) == 0 {
+			fmt.Fprintln(out, "    (no candidates)")
+		}
+		for i, c := range s.Candidates {
+			if i == 3 {
+				break
+			}
+			fmt.Fprintf(out, "    %-40s  %s (score %.1f)\n", c.Owner.String(), pluralize(c.Commits, "commit"), c.Score)
+		}
+	}
+}
+
+func printSuggestedRules(out io.Writer, suggestions []codeowners.OwnerSuggestion, maxOwners int) {
+	width := 0
+	for _, s := range suggestions {
+		if len(s.Pattern) > width {
+			width = len(s.Pattern)
+		}
+	}
+
+	for _, s := range suggestions {
+		if s.Pattern == "" {
+			fmt.Fprintf(out, "# /%s can't be used in a pattern as it contains \"#\"\n", s.Paths[0])
+			continue
+		}
+		if len(s.Candidates) == 0 {
+			fmt.Fprintf(out, "# %s has no suggested owners\n", s.Pattern)
+			continue
+		}
+
+		owners := make([]codeowners.Owner, 0, maxOwners)
+		for i := 0; i < len(s.Candidates) && i < maxOwners; i++ {
+			owners = append(owners, s.Candidates[i].Owner)
+		}
+		fmt.Fprintf(out, "%-*s  %s\n", width, s.Pattern, formatOwners(owners))
+	}
+}
+
+// loadAuthorMap reads a YAML file mapping author email addresses to owners,
+// e.g. `alice@example.com: "@alice"`.
+func loadAuthorMap(path string) (codeowners.AuthorMap, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var raw map[string]string
+	if err := yaml.Unmarshal(data, &raw); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+
+	authors := codeowners.AuthorMap{}
+	for email, ownerStr := range raw {
+		owner, err := parseOwner(ownerStr)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", path, err)
+		}
+		authors[strings.ToLower(email)] = owner
+	}
+	return authors, nil
+}
+
+// parseOwner parses an owner string such as "@org/team" using the default
+// owner matchers.
+func parseOwner(s string) (codeowners.Owner, error) {
+	for _, m := range codeowners.DefaultOwnerMatchers {
+		owner, err := m.Match(s)
+		if errors.Is(err, codeowners.ErrNoMatch) {
+			continue
+		}
+		return owner, err
+	}
+	return codeowners.Owner{}, codeowners.ErrInvalidOwnerFormat{Owner: s}
+}
+
+// nonEmpty returns the values provided, or the default if there aren't any.
+func nonEmpty(values []string, def string) []string {
+	if len(values) == 0 {
+		return []string{def}
+	}
+	return values
+}
@@ -0,0 +1,82 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/hmarr/codeowners"
+	flag "github.com/spf13/pflag"
+)
+
+// runValidate implements the validate subcommand, which checks a CODEOWNERS
+// file for lines GitHub would skip or interpret differently.
+func runValidate(args []string) error {
+	var (
+		codeownersPath string
+		helpFlag       bool
+	)
+	flags := flag.NewFlagSet("validate", flag.ContinueOnError)
+	flags.StringVarP(&codeownersPath, "file", "f", "", "CODEOWNERS file path")
+	flags.BoolVarP(&helpFlag, "help", "h", false, "show this help message")
+	flags.Usage = func() {
+		fmt.Fprintf(os.Stderr, "usage: codeowners validate [options]\n\n")
+		fmt.Fprintf(os.Stderr, "Strictly checks a CODEOWNERS file against the rules GitHub enforces, reporting\n")
+		fmt.Fprintf(os.Stderr, "lines GitHub skips because they're invalid, and syntax GitHub doesn't support\n")
+		fmt.Fprintf(os.Stderr, "such as \"!\" negation and character ranges. Exits with a non-zero status if\n")
+		fmt.Fprintf(os.Stderr, "any problems are found.\n\n")
+		flags.PrintDefaults()
+	}
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	if helpFlag {
+		flags.Usage()
+		return nil
+	}
+	if flags.NArg() > 0 {
+		flags.Usage()
+		return fmt.Errorf("unexpected arguments")
+	}
+
+	// Paths found at a standard location are shown relative to the root
+	path, displayPath := codeownersPath, codeownersPath
+	if path == "" {
+		repo, err := codeowners.FindRepository(".")
+		if err != nil {
+			return fmt.Errorf("--file is required outside a git repository")
+		}
+		relPath, err := repo.FindFileAtStandardLocation("")
+		if err != nil {
+			return err
+		}
+		if relPath == "" {
+			return fmt.Errorf("could not find CODEOWNERS file at any of the standard locations")
+		}
+		path = filepath.Join(repo.Root, filepath.FromSlash(relPath))
+		displayPath = relPath
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	diagnostics, err := codeowners.ValidateFile(f)
+	if err != nil {
+		return err
+	}
+
+	for _, d := range diagnostics {
+		fmt.Printf("%s:%d: %s\n", displayPath, d.LineNumber, d.Message)
+	}
+	switch len(diagnostics) {
+	case 0:
+		return nil
+	case 1:
+		return fmt.Errorf("found 1 problem")
+	default:
+		return fmt.Errorf("found %d problems", len(diagnostics))
+	}
+}
@@ -5,67 +5,74 @@
 // the CODEOWNERS file format into rulesets, which may then be used to determine
 // the ownership of files.
 //
-// Usage
+// # Usage
 //
 // To find the owner of a given file, parse a CODEOWNERS file and call Match()
 // on the resulting ruleset.
-//  ruleset, err := codeowners.ParseFile(file)
-//  if err != nil {
-//  	log.Fatal(err)
-//  }
 //
-//  rule, err := ruleset.Match("path/to/file")
-//  if err != nil {
-//  	log.Fatal(err)
-//  }
+//	ruleset, err := codeowners.ParseFile(file)
+//	if err != nil {
+//		log.Fatal(err)
+//	}
 //
-// Command line interface
+//	rule, err := ruleset.Match("path/to/file")
+//	if err != nil {
+//		log.Fatal(err)
+//	}
+//
+// # Command line interface
 //
 // A command line interface is also available in the cmd/codeowners package.
-// When run, it will walk the directory tree showing the code owners for each
-// file encountered. The help flag lists available options.
+// When run, it will list the files tracked by git (or walk the directory tree
+// outside of a git repository) showing the code owners for each file
+// encountered. The help flag lists available options.
 //
-//  $ codeowners --help
+//	$ codeowners --help
 package codeowners
 
 import (
 	"fmt"
 	"os"
-	"os/exec"
 	"path/filepath"
 	"strings"
 )
 
 // LoadFileFromStandardLocation loads and parses a CODEOWNERS file at one of the
-// standard locations for CODEOWNERS files (.github/, ./, docs/, .gitlab/). If
-// run from a git repository, all paths are relative to the repository root.
-func LoadFileFromStandardLocation() (Ruleset, error) {
+// standard locations for CODEOWNERS files (.github/, ./, docs/, .gitlab/,
+// .bitbucket/). If run from a git repository, all paths are relative to the
+// repository root. The dialect is detected from the location, as in LoadFile.
+func LoadFileFromStandardLocation(options ...ParseOption) (Ruleset, error) {
 	path := findFileAtStandardLocation()
 	if path == "" {
 		return nil, fmt.Errorf("could not find CODEOWNERS file at any of the standard locations")
 	}
-	return LoadFile(path)
+	return LoadFile(path, options...)
 }
 
-// LoadFile loads and parses a CODEOWNERS file at the path specified.
-func LoadFile(path string) (Ruleset, error) {
+// LoadFile loads and parses a CODEOWNERS file at the path specified. Unless the
+// WithDialect option is given, the dialect is detected from the path with
+// DetectDialect.
+func LoadFile(path string, options ...ParseOption) (Ruleset, error) {
 	f, err := os.Open(path)
 	if err != nil {
 		return nil, err
 	}
-	return ParseFile(f)
+	defer f.Close()
+	return ParseFile(f, append([]ParseOption{WithDialect(DetectDialect(path))}, options...)...)
 }
 
 // standardLocations lists the locations a CODEOWNERS file may live in, in the
 // order they should be searched. GitHub searches .github/, the root, then docs/,
-// while GitLab searches the root, docs/, then .gitlab/. This ordering is
-// consistent with both: it preserves each provider's relative precedence, so
-// there's no need to know which provider a repository belongs to.
+// while GitLab searches the root, docs/, then .gitlab/, and Bitbucket only
+// looks in .bitbucket/. This ordering is consistent with all of them: it
+// preserves each provider's relative precedence (see Dialect.StandardLocations),
+// so there's no need to know which provider a repository belongs to.
 var standardLocations = []string{
 	".github/CODEOWNERS",
 	"CODEOWNERS",
 	"docs/CODEOWNERS",
 	".gitlab/CODEOWNERS",
+	".bitbucket/CODEOWNERS",
 }
 
 // findFileAtStandardLocation loops through the standard locations for CODEOWNERS
@@ -105,11 +112,11 @@ func fileExists(path string) bool {
 // we're currently in one. If we're not in a git repository, the boolean return
 // value is false.
 func findRepositoryRoot() (string, bool) {
-	output, err := exec.Command("git", "rev-parse", "--show-toplevel").Output()
+	repo, err := FindRepository(".")
 	if err != nil {
 		return "", false
 	}
-	return strings.TrimSpace(string(output)), true
+	return repo.Root, true
 }
 
 // Ruleset is a collection of CODEOWNERS rules.
@@ -117,35 +124,107 @@ type Ruleset []Rule
 
 // Match finds the last rule in the ruleset that matches the path provided. When
 // determining the ownership of a file using CODEOWNERS, order matters, and the
-// last matching rule takes precedence.
+// last matching rule takes precedence. Excluded rules are skipped, but if one
+// in the same GitLab section as the matching rule matches too, the path is
+// unowned and nil is returned.
 func (r Ruleset) Match(path string) (*Rule, error) {
 	for i := len(r) - 1; i >= 0; i-- {
 		rule := &r[i]
+		if rule.Excluded {
+			continue
+		}
 		match, err := rule.Match(path)
-		if match || err != nil {
+		if err != nil {
 			return rule, err
 		}
+		if !match {
+			continue
+		}
+
+		excluded, err := r.excludes(sectionKey(rule.Section), path)
+		if excluded || err != nil {
+			return nil, err
+		}
+		return rule, nil
 	}
 	return nil, nil
 }
 
+// excludes checks whether an excluded rule in the section provided matches the
+// path.
+func (r Ruleset) excludes(section, path string) (bool, error) {
+	for _, rule := range r {
+		if !rule.Excluded || sectionKey(rule.Section) != section {
+			continue
+		}
+		if match, err := rule.Match(path); match || err != nil {
+			return match, err
+		}
+	}
+	return false, nil
+}
+
 // Rule is a CODEOWNERS rule that maps a gitignore-style path pattern to a set
 // of owners.
 type Rule struct {
 	Owners     []Owner
 	Comment    string
 	LineNumber int
-	pattern    pattern
+	// Section is the GitLab section the rule belongs to, or nil if it's not in
+	// one.
+	Section *Section
+	// Excluded is set for GitLab rules whose patterns start with "!". Paths
+	// they match are unowned in their section, wherever the rule appears.
+	Excluded bool
+	pattern  Pattern
+}
+
+// NewRule creates a rule from a gitignore-style path pattern and a set of
+// owners. A rule with no owners leaves the paths it matches unowned.
+func NewRule(pattern string, owners []Owner) (Rule, error) {
+	if pattern == "" {
+		return Rule{}, fmt.Errorf("empty pattern")
+	}
+	pat, err := newPattern(pattern, patternOptions{})
+	if err != nil {
+		return Rule{}, err
+	}
+	return Rule{pattern: pat, Owners: owners}, nil
 }
 
-// RawPattern returns the rule's gitignore-style path pattern.
+// String returns the rule as it would appear in a CODEOWNERS file.
+func (r Rule) String() string {
+	var b strings.Builder
+	if r.Excluded {
+		b.WriteByte('!')
+	}
+	b.WriteString(r.pattern.pattern)
+	for _, o := range r.Owners {
+		b.WriteByte(' ')
+		b.WriteString(o.String())
+	}
+	if r.Comment != "" {
+		b.WriteString(" # ")
+		b.WriteString(r.Comment)
+	}
+	return b.String()
+}
+
+// RawPattern returns the rule's gitignore-style path pattern, without the "!"
+// of excluded rules.
 func (r Rule) RawPattern() string {
 	return r.pattern.pattern
 }
 
-// Match tests whether the provided matches the rule's pattern.
+// Pattern returns the rule's compiled pattern.
+func (r Rule) Pattern() Pattern {
+	return r.pattern
+}
+
+// Match tests whether the provided matches the rule's pattern. It doesn't take
+// Excluded into account.
 func (r Rule) Match(path string) (bool, error) {
-	return r.pattern.match(path)
+	return r.pattern.Match(path), nil
 }
 
 const (
@@ -161,16 +240,20 @@ const (
 type Owner struct {
 	// Value is the name of the owner: the email addres, team name, or username.
 	Value string
-	// Type will be one of 'email', 'team', or 'username'.
+	// Type will be one of 'email', 'team', or 'username', or for other
+	// dialects, 'role', 'group', or 'bitbucket-group'.
 	Type string
 }
 
 // String returns a string representation of the owner. For email owners, it
 // simply returns the email address. For user and team owners it prepends an '@'
-// to the owner.
+// to the owner, and for role and Bitbucket group owners it prepends '@@'.
 func (o Owner) String() string {
-	if o.Type == EmailOwner {
+	switch o.Type {
+	case EmailOwner:
 		return o.Value
+	case RoleOwner, BitbucketGroupOwner:
+		return "@@" + o.Value
 	}
 	return "@" + o.Value
 }
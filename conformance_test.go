@@ -0,0 +1,346 @@
+package codeowners
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"math/rand"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+	"unicode/utf8"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var (
+	conformanceCases = flag.Int("conformance-cases", 500, "number of random patterns to check against git")
+	conformanceSeed  = flag.Int64("conformance-seed", 1, "seed for the random patterns checked against git")
+)
+
+// TestGitConformance checks that patterns match the same paths as they do in
+// git. Any discrepancies are printed as cases for testdata/patterns.json. Run
+// more random patterns with, e.g.:
+//
+//	go test -run TestGitConformance -conformance-cases 100000 -conformance-seed 2
+func TestGitConformance(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git binary not available")
+	}
+
+	data, err := os.ReadFile("testdata/patterns.json")
+	require.NoError(t, err)
+	var cases []conformanceCase
+	require.NoError(t, json.Unmarshal(data, &cases))
+	cases = append(cases, randomConformanceCases(rand.New(rand.NewSource(*conformanceSeed)), *conformanceCases)...)
+
+	discrepancies, err := checkGitConformance(cases)
+	require.NoError(t, err)
+	if len(discrepancies) > 0 {
+		output, err := json.MarshalIndent(discrepancies, "", "   ")
+		require.NoError(t, err)
+		t.Errorf("found %d patterns that don't match the same paths as git:\n%s", len(discrepancies), output)
+	}
+}
+
+func TestGitComparablePattern(t *testing.T) {
+	tests := map[string]bool{
+		"*.md":          true,
+		"/docs/**/*.md": true,
+		`a\ b`:          true,
+		"!*.md":         false,
+		"/apps/[id]/":   false,
+		"/docs/*":       false,
+		"/*":            false,
+		"*":             true,
+		"docs/**/":      false,
+		"a/***":         false,
+	}
+	for pattern, comparable := range tests {
+		assert.Equal(t, comparable, gitComparablePattern(pattern), pattern)
+	}
+}
+
+func TestRandomConformanceCases(t *testing.T) {
+	cases := randomConformanceCases(rand.New(rand.NewSource(1)), 10)
+	assert.Len(t, cases, 10)
+	assert.Equal(t, cases, randomConformanceCases(rand.New(rand.NewSource(1)), 10))
+}
+
+// conformanceCase is a pattern and whether it matches each of a set of paths,
+// in the format of the cases in testdata/patterns.json.
+type conformanceCase struct {
+	Name    string          `json:"name"`
+	Pattern string          `json:"pattern"`
+	Paths   map[string]bool `json:"paths"`
+}
+
+// checkGitConformance matches the paths in each case against its pattern with
+// both this library and git's own matching, by running `git check-ignore
+// --no-index` with the pattern in a .gitignore file. The expected results in
+// the cases are ignored. A case is returned for each pattern where the two
+// disagree, containing the paths they disagree on and git's results for them,
+// so it can be added to the test suite.
+//
+// Patterns that can't be compared are skipped: those that mean something else
+// in a .gitignore file (starting with "!" or "#", or ending with a space), those
+// with square brackets (which GitHub matches literally, but git treats as
+// character classes), and those this library rejects. So are patterns where
+// GitHub deliberately differs from git: a final "*" segment, as in "/docs/*",
+// doesn't match the contents of subdirectories, and a final "**/" matches
+// files as well as directories. Git's "?" matches a single byte rather than a
+// single character, so paths with non-ASCII characters aren't compared for
+// patterns containing it. Requires the git binary.
+func checkGitConformance(cases []conformanceCase) ([]conformanceCase, error) {
+	var comparable []conformanceCase
+	for _, c := range cases {
+		if gitComparablePattern(c.Pattern) {
+			comparable = append(comparable, c)
+		}
+	}
+	if len(comparable) == 0 {
+		return nil, nil
+	}
+
+	dir, err := os.MkdirTemp("", "codeowners-conformance")
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(dir)
+
+	// Each case gets a subdirectory with its pattern in a .gitignore file, so
+	// every path can be checked by a single git process
+	var input bytes.Buffer
+	var patterns []Pattern
+	for i, c := range comparable {
+		pattern, err := newPattern(c.Pattern, patternOptions{})
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", c.Name, err)
+		}
+		patterns = append(patterns, pattern)
+
+		caseDir := filepath.Join(dir, fmt.Sprint(i))
+		if err := os.Mkdir(caseDir, 0o755); err != nil {
+			return nil, err
+		}
+		if err := os.WriteFile(filepath.Join(caseDir, ".gitignore"), []byte(c.Pattern+"\n"), 0o644); err != nil {
+			return nil, err
+		}
+		for _, path := range gitComparablePaths(c) {
+			fmt.Fprintf(&input, "%d/%s\x00", i, path)
+		}
+	}
+
+	ignored, err := gitCheckIgnore(dir, &input)
+	if err != nil {
+		return nil, err
+	}
+
+	var discrepancies []conformanceCase
+	for i, c := range comparable {
+		disagreements := map[string]bool{}
+		for _, path := range gitComparablePaths(c) {
+			gitMatch := ignored[fmt.Sprintf("%d/%s", i, path)]
+			if patterns[i].Match(path) != gitMatch {
+				disagreements[path] = gitMatch
+			}
+		}
+		if len(disagreements) > 0 {
+			discrepancies = append(discrepancies, conformanceCase{
+				Name:    "git conformance: " + c.Name,
+				Pattern: c.Pattern,
+				Paths:   disagreements,
+			})
+		}
+	}
+	return discrepancies, nil
+}
+
+// gitComparablePattern checks whether a pattern has the same meaning in a
+// .gitignore file as in a CODEOWNERS file.
+func gitComparablePattern(pattern string) bool {
+	if pattern == "" || pattern == "/" || pattern[0] == '!' || pattern[0] == '#' ||
+		strings.HasSuffix(pattern, " ") || strings.ContainsAny(pattern, "[]\n") {
+		return false
+	}
+	if trimmed := strings.TrimPrefix(pattern, "/"); strings.HasSuffix(trimmed, "/*") || trimmed == "*" && pattern[0] == '/' ||
+		strings.HasSuffix(pattern, "**/") {
+		return false
+	}
+	_, err := newPattern(pattern, patternOptions{})
+	return err == nil
+}
+
+// gitComparablePaths returns the paths in a case that git matches the same way
+// as this library would, in order.
+func gitComparablePaths(c conformanceCase) []string {
+	var paths []string
+	for _, path := range sortedPaths(c.Paths) {
+		if !strings.Contains(c.Pattern, "?") || isASCII(path) {
+			paths = append(paths, path)
+		}
+	}
+	return paths
+}
+
+func isASCII(s string) bool {
+	for i := 0; i < len(s); i++ {
+		if s[i] >= utf8.RuneSelf {
+			return false
+		}
+	}
+	return true
+}
+
+// gitCheckIgnore runs `git check-ignore --no-index` in a new repository at dir
+// for the NUL-separated paths in input, returning the set of paths it reports
+// as ignored. Git's system and global config, including any global excludes
+// file, are ignored so they can't affect the results.
+func gitCheckIgnore(dir string, input *bytes.Buffer) (map[string]bool, error) {
+	env := append(os.Environ(), "GIT_CONFIG_NOSYSTEM=1", "GIT_CONFIG_GLOBAL="+os.DevNull)
+	gitArgs := []string{"-C", dir, "-c", "core.excludesFile=" + os.DevNull, "-c", "core.ignoreCase=false"}
+
+	init := exec.Command("git", append(gitArgs, "init", "--quiet")...)
+	init.Env = env
+	if output, err := init.CombinedOutput(); err != nil {
+		return nil, fmt.Errorf("git init: %s", bytes.TrimSpace(output))
+	}
+
+	cmd := exec.Command("git", append(gitArgs, "check-ignore", "--no-index", "--stdin", "-z", "--verbose", "--non-matching")...)
+	cmd.Env = env
+	cmd.Stdin = input
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	output, err := cmd.Output()
+	// check-ignore exits with status 1 if no paths are ignored
+	if exitErr, ok := err.(*exec.ExitError); err != nil && !(ok && exitErr.ExitCode() == 1) {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return nil, fmt.Errorf("git check-ignore: %s", msg)
+		}
+		return nil, fmt.Errorf("git check-ignore: %w", err)
+	}
+
+	// Each path is reported as four fields: the source of the matching
+	// pattern, its line number, the pattern, and the path. The first three are
+	// empty if no pattern matched.
+	fields := bytes.Split(bytes.TrimSuffix(output, []byte{0}), []byte{0})
+	if len(fields)%4 != 0 {
+		return nil, fmt.Errorf("git check-ignore: unexpected output %q", output)
+	}
+	ignored := map[string]bool{}
+	for i := 0; i < len(fields); i += 4 {
+		if len(fields[i]) > 0 {
+			ignored[string(fields[i+3])] = true
+		}
+	}
+	return ignored, nil
+}
+
+func sortedPaths(paths map[string]bool) []string {
+	sorted := make([]string, 0, len(paths))
+	for path := range paths {
+		sorted = append(sorted, path)
+	}
+	sort.Strings(sorted)
+	return sorted
+}
+
+// conformanceSegments are the building blocks of random patterns, chosen to
+// exercise wildcards, escaping, and characters with special meanings elsewhere.
+var conformanceSegments = []string{
+	"a", "b", "ab", "foo", ".x", "a.b", "*", "**", "a*", "*b", "*.b", "?", "a?", "?b",
+	`\*`, `a\?`, `\\`, `a\ b`, "{a,b}", "a-b", "a+b", "(a)", "a|b", "a~", "a^b", "a=b,c", "$a%", "&'", "é*", "日本",
+}
+
+// conformanceNames are path segments used alongside ones derived from the
+// pattern, so paths that shouldn't match are tested too.
+var conformanceNames = []string{
+	"a", "b", "ab", "foo", ".x", "a.b", "x.b", "*", "?", `\`, "a b", "{a,b}", "a-b", "a+b", "(a)", "a|b", "a~", "a^b", "ba", "a=b,c", "$a%", "&'", "é", "éa", "日本",
+}
+
+// randomConformanceCases generates n cases with random patterns, each with a
+// selection of paths that may or may not match, for checkGitConformance.
+// Expected results aren't known, so every path is marked as not matching. The
+// same source gives the same cases.
+func randomConformanceCases(rng *rand.Rand, n int) []conformanceCase {
+	cases := make([]conformanceCase, 0, n)
+	for i := 0; i < n; i++ {
+		segs := make([]string, 1+rng.Intn(4))
+		for j := range segs {
+			segs[j] = conformanceSegments[rng.Intn(len(conformanceSegments))]
+		}
+		pattern := strings.Join(segs, "/")
+		if rng.Intn(3) == 0 {
+			pattern = "/" + pattern
+		}
+		if rng.Intn(4) == 0 {
+			pattern += "/"
+		}
+		pattern = strings.ReplaceAll(pattern, "***", "**")
+
+		paths := map[string]bool{}
+		for j := 0; j < 4; j++ {
+			paths[conformancePath(rng, segs)] = false
+		}
+		for j := 0; j < 4; j++ {
+			names := make([]string, 1+rng.Intn(4))
+			for k := range names {
+				names[k] = conformanceNames[rng.Intn(len(conformanceNames))]
+			}
+			paths[strings.Join(names, "/")] = false
+		}
+		cases = append(cases, conformanceCase{Name: fmt.Sprintf("random %d", i), Pattern: pattern, Paths: paths})
+	}
+	return cases
+}
+
+// conformancePath generates a path that's likely to match a pattern made of
+// the segments provided, by filling in its wildcards and sometimes adding
+// directories before or after it.
+func conformancePath(rng *rand.Rand, segs []string) string {
+	var names []string
+	if rng.Intn(4) == 0 {
+		names = append(names, conformanceNames[rng.Intn(len(conformanceNames))])
+	}
+	for _, seg := range segs {
+		if seg == "**" {
+			for k := rng.Intn(3); k > 0; k-- {
+				names = append(names, conformanceNames[rng.Intn(len(conformanceNames))])
+			}
+			continue
+		}
+
+		var b strings.Builder
+		escaped := false
+		for _, ch := range seg {
+			switch {
+			case escaped:
+				escaped = false
+				b.WriteRune(ch)
+			case ch == '\\':
+				escaped = true
+			case ch == '*':
+				b.WriteString([]string{"", "x", "ab", ".b"}[rng.Intn(4)])
+			case ch == '?':
+				b.WriteString([]string{"x", "-", "?"}[rng.Intn(3)])
+			default:
+				b.WriteRune(ch)
+			}
+		}
+		if b.Len() > 0 {
+			names = append(names, b.String())
+		}
+	}
+	if rng.Intn(3) == 0 {
+		names = append(names, conformanceNames[rng.Intn(len(conformanceNames))])
+	}
+	if len(names) == 0 {
+		names = append(names, "a")
+	}
+	return strings.Join(names, "/")
+}
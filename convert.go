@@ -0,0 +1,173 @@
+package codeowners
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Convert translates a ruleset parsed as the from dialect into the to dialect,
+// so that it gives the same owners when written out and read by the other
+// service. Patterns are rewritten where the dialects match them differently,
+// and owners the to dialect doesn't understand are dropped. Anything that
+// can't be converted exactly, such as GitLab sections, is reported as a
+// warning.
+func (r Ruleset) Convert(from, to Dialect) (Ruleset, []string, error) {
+	var warnings []string
+	if from == to {
+		return r, warnings, nil
+	}
+
+	var section *Section
+	converted := make(Ruleset, 0, len(r))
+	for _, rule := range r {
+		if rule.Section != section {
+			section = rule.Section
+			if section != nil && to != GitLab {
+				warnings = append(warnings, sectionWarnings(section, to)...)
+			}
+		}
+
+		patterns, patternWarnings, err := convertGlobs(rule, to)
+		if err != nil {
+			return nil, nil, err
+		}
+		warnings = append(warnings, patternWarnings...)
+
+		var owners []Owner
+		for _, o := range rule.Owners {
+			// Owners are matched again as their type may differ between dialects
+			converted, err := newOwner(o.String(), to.OwnerMatchers())
+			if err != nil {
+				warnings = append(warnings, fmt.Sprintf("line %d: dropping owner %s from %q as %s doesn't support it",
+					rule.LineNumber, o, rule.RawPattern(), to))
+				continue
+			}
+			owners = append(owners, converted)
+		}
+		if len(owners) == 0 && len(rule.Owners) > 0 {
+			warnings = append(warnings, fmt.Sprintf("line %d: %q has no owners left, so the files it matches will be unowned",
+				rule.LineNumber, rule.RawPattern()))
+		}
+		if rule.Excluded && to != GitLab {
+			warnings = append(warnings, fmt.Sprintf("line %d: %s doesn't support excluding %q, so it's converted to a rule without owners, which only applies if no later rule matches",
+				rule.LineNumber, to, rule.RawPattern()))
+		}
+
+		patternOpts := to.patternOptions()
+		patternOpts.caseInsensitive = rule.pattern.opts.caseInsensitive
+		patternOpts.normalizeUnicode = rule.pattern.opts.normalizeUnicode
+		for _, pattern := range patterns {
+			pat, err := newPattern(convertPattern(pattern, from, to), patternOpts)
+			if err != nil {
+				return nil, nil, fmt.Errorf("line %d: %w", rule.LineNumber, err)
+			}
+			newRule := Rule{
+				pattern:    pat,
+				Owners:     owners,
+				Comment:    rule.Comment,
+				LineNumber: rule.LineNumber,
+			}
+			if to == GitLab {
+				newRule.Section = rule.Section
+				newRule.Excluded = rule.Excluded
+			}
+			converted = append(converted, newRule)
+		}
+	}
+	return converted, warnings, nil
+}
+
+// sectionWarnings describes what's lost when a GitLab section is converted to a
+// dialect without sections.
+func sectionWarnings(section *Section, to Dialect) []string {
+	prefix := fmt.Sprintf("line %d: section %q", section.LineNumber, section.Name)
+	warnings := []string{fmt.Sprintf("%s isn't supported by %s, so its rules are merged with the rest of the file and no longer combine owners with other sections",
+		prefix, to)}
+	if section.Optional {
+		warnings = append(warnings, fmt.Sprintf("%s is optional, but %s will require approval", prefix, to))
+	}
+	if section.Approvals > 0 {
+		warnings = append(warnings, fmt.Sprintf("%s requires %d approvals, which %s can't express", prefix, section.Approvals, to))
+	}
+	return warnings
+}
+
+// convertGlobs rewrites a rule's pattern for the character classes and brace
+// expansion the to dialect supports. Braces are expanded into several patterns
+// if they aren't supported, and brackets and braces that were literal are
+// escaped if they would no longer be.
+func convertGlobs(rule Rule, to Dialect) ([]string, []string, error) {
+	var warnings []string
+	fromOpts, toOpts := rule.pattern.opts, to.patternOptions()
+	pattern := rule.RawPattern()
+	if !fromOpts.charClasses && toOpts.charClasses {
+		pattern = escapeChars(pattern, "[]")
+	}
+	if !fromOpts.braces && toOpts.braces {
+		pattern = escapeChars(pattern, "{}")
+	}
+	if fromOpts.charClasses && !toOpts.charClasses && hasCharClass(pattern) {
+		warnings = append(warnings, fmt.Sprintf("line %d: %s doesn't support character classes, so %q may not match as expected",
+			rule.LineNumber, to, rule.RawPattern()))
+	}
+
+	patterns := []string{pattern}
+	if fromOpts.braces && !toOpts.braces {
+		var err error
+		if patterns, err = expandBraces(pattern, fromOpts.charClasses); err != nil {
+			return nil, nil, fmt.Errorf("line %d: %w", rule.LineNumber, err)
+		}
+		if len(patterns) > 1 {
+			warnings = append(warnings, fmt.Sprintf("line %d: %s doesn't support brace expansion, so %q is split into %d rules",
+				rule.LineNumber, to, rule.RawPattern(), len(patterns)))
+		}
+	}
+	return patterns, warnings, nil
+}
+
+// escapeChars escapes the characters in chars that aren't already escaped in
+// a pattern.
+func escapeChars(pattern, chars string) string {
+	var b strings.Builder
+	escaped := false
+	for _, ch := range pattern {
+		if !escaped && strings.ContainsRune(chars, ch) {
+			b.WriteByte('\\')
+		}
+		escaped = !escaped && ch == '\\'
+		b.WriteRune(ch)
+	}
+	return b.String()
+}
+
+// hasCharClass checks whether a pattern contains a character class.
+func hasCharClass(pattern string) bool {
+	for i := 0; i < len(pattern); i++ {
+		switch pattern[i] {
+		case '\\':
+			i++
+		case '[':
+			if _, n, _ := charClassRegex(pattern[i:]); n > 0 {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// convertPattern rewrites a pattern so it matches the same paths in the to
+// dialect as it did in the from dialect. Only relative patterns with a slash
+// before the end differ: GitLab matches them at any depth, while GitHub and
+// Bitbucket anchor them to the root.
+func convertPattern(pattern string, from, to Dialect) string {
+	if from.patternOptions().anyDepth == to.patternOptions().anyDepth ||
+		strings.HasPrefix(pattern, "/") ||
+		strings.HasPrefix(pattern, "**/") ||
+		!strings.Contains(strings.TrimSuffix(pattern, "/"), "/") {
+		return pattern
+	}
+	if to.patternOptions().anyDepth {
+		return "/" + pattern
+	}
+	return "**/" + pattern
+}
@@ -0,0 +1,125 @@
+package codeowners
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestConvertGitHubToGitLab(t *testing.T) {
+	ruleset, err := ParseFile(bytes.NewBufferString("* @org/everyone\n*.md @docs\ndocs/README.md @alice # Readme\n/src/ @bob\n"))
+	require.NoError(t, err)
+
+	converted, warnings, err := ruleset.Convert(GitHub, GitLab)
+	require.NoError(t, err)
+	assert.Empty(t, warnings)
+
+	var patterns []string
+	for _, rule := range converted {
+		patterns = append(patterns, rule.RawPattern())
+	}
+	assert.Equal(t, []string{"*", "*.md", "/docs/README.md", "/src/"}, patterns)
+	assert.Equal(t, "Readme", converted[2].Comment)
+
+	var buf bytes.Buffer
+	require.NoError(t, WriteFile(&buf, converted))
+	parsed, err := ParseFile(&buf, WithDialect(GitLab))
+	require.NoError(t, err)
+	for _, path := range []string{"docs/README.md", "src/docs/README.md", "src/main.go", "go.mod"} {
+		want, err := ruleset.Match(path)
+		require.NoError(t, err)
+		got, err := parsed.MatchSections(path)
+		require.NoError(t, err)
+		require.Len(t, got, 1, path)
+		assert.Equal(t, ownerStrings(want.Owners), ownerStrings(got[0].Owners), path)
+	}
+}
+
+func TestConvertGitLabToGitHub(t *testing.T) {
+	file := "* @everyone\n\n^[Docs][2] @docs-team\ndocs/\nguides/intro.md @alice\n"
+	ruleset, err := ParseFile(bytes.NewBufferString(file), WithDialect(GitLab))
+	require.NoError(t, err)
+
+	converted, warnings, err := ruleset.Convert(GitLab, GitHub)
+	require.NoError(t, err)
+	assert.Equal(t, []string{
+		`line 3: section "Docs" isn't supported by github, so its rules are merged with the rest of the file and no longer combine owners with other sections`,
+		`line 3: section "Docs" is optional, but github will require approval`,
+		`line 3: section "Docs" requires 2 approvals, which github can't express`,
+	}, warnings)
+
+	var buf bytes.Buffer
+	require.NoError(t, WriteFile(&buf, converted))
+	assert.Equal(t, ""+
+		"*                   @everyone\n"+
+		"docs/               @docs-team\n"+
+		"**/guides/intro.md  @alice\n", buf.String())
+}
+
+func TestConvertOwners(t *testing.T) {
+	ruleset, err := ParseFile(bytes.NewBufferString("* @@reviewers @alice\n/src/ @@core\n"), WithDialect(Bitbucket))
+	require.NoError(t, err)
+
+	converted, warnings, err := ruleset.Convert(Bitbucket, GitHub)
+	require.NoError(t, err)
+	assert.Equal(t, []string{
+		`line 1: dropping owner @@reviewers from "*" as github doesn't support it`,
+		`line 2: dropping owner @@core from "/src/" as github doesn't support it`,
+		`line 2: "/src/" has no owners left, so the files it matches will be unowned`,
+	}, warnings)
+	assert.Equal(t, []Owner{{Value: "alice", Type: UsernameOwner}}, converted[0].Owners)
+	assert.Empty(t, converted[1].Owners)
+}
+
+func TestConvertGitLabOwners(t *testing.T) {
+	ruleset, err := ParseFile(bytes.NewBufferString("* @@maintainer @org/team @org/group/subgroup\n"), WithDialect(GitLab))
+	require.NoError(t, err)
+
+	converted, warnings, err := ruleset.Convert(GitLab, GitHub)
+	require.NoError(t, err)
+	assert.Equal(t, []string{
+		`line 1: dropping owner @@maintainer from "*" as github doesn't support it`,
+		`line 1: dropping owner @org/group/subgroup from "*" as github doesn't support it`,
+	}, warnings)
+	assert.Equal(t, []Owner{{Value: "org/team", Type: TeamOwner}}, converted[0].Owners)
+}
+
+func TestConvertExclusions(t *testing.T) {
+	ruleset, err := ParseFile(bytes.NewBufferString("* @everyone\n!/generated/\n"), WithDialect(GitLab))
+	require.NoError(t, err)
+
+	converted, warnings, err := ruleset.Convert(GitLab, GitHub)
+	require.NoError(t, err)
+	assert.Equal(t, []string{
+		`line 2: github doesn't support excluding "/generated/", so it's converted to a rule without owners, which only applies if no later rule matches`,
+	}, warnings)
+	assert.False(t, converted[1].Excluded)
+	assert.Equal(t, "/generated/", converted[1].String())
+}
+
+func TestConvertExtendedPatterns(t *testing.T) {
+	ruleset, err := ParseFile(bytes.NewBufferString("/*.{js,ts} @frontend\n/v[0-9]/ @api\n"), WithDialect(GitLab))
+	require.NoError(t, err)
+
+	converted, warnings, err := ruleset.Convert(GitLab, GitHub)
+	require.NoError(t, err)
+	assert.Equal(t, []string{
+		`line 1: github doesn't support brace expansion, so "/*.{js,ts}" is split into 2 rules`,
+		`line 2: github doesn't support character classes, so "/v[0-9]/" may not match as expected`,
+	}, warnings)
+	assert.Equal(t, []string{"/*.js", "/*.ts", "/v[0-9]/"}, rawPatterns(converted))
+	assert.Equal(t, 1, converted[1].LineNumber)
+
+	// Literal brackets and braces are escaped for GitLab
+	ruleset, err = ParseFile(bytes.NewBufferString("/apps/[id]/{a}.ts @web\n"))
+	require.NoError(t, err)
+	converted, warnings, err = ruleset.Convert(GitHub, GitLab)
+	require.NoError(t, err)
+	assert.Empty(t, warnings)
+	assert.Equal(t, []string{`/apps/\[id\]/\{a\}.ts`}, rawPatterns(converted))
+	rule, err := converted.Match("apps/[id]/{a}.ts")
+	require.NoError(t, err)
+	assert.NotNil(t, rule)
+}
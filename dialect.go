@@ -0,0 +1,294 @@
+package codeowners
+
+import (
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Dialect is a flavour of the CODEOWNERS format, as understood by one of the
+// code hosting services that support it. The dialect decides which syntax is
+// accepted, which kinds of owners are recognised, and how patterns match.
+type Dialect string
+
+const (
+	// GitHub is the dialect understood by GitHub, and the default. A file's
+	// owners come from the last rule that matches it.
+	GitHub Dialect = "github"
+	// GitLab is the dialect understood by GitLab. Rules may be grouped into
+	// sections, and the owners of a file are combined from the last matching
+	// rule in each section (see Ruleset.MatchSections). Relative patterns
+	// match at any depth even if they contain a slash, so "docs/README.md"
+	// matches "src/docs/README.md".
+	GitLab Dialect = "gitlab"
+	// Bitbucket is the dialect understood by Bitbucket, which matches like
+	// GitHub but supports "@@group" owners.
+	Bitbucket Dialect = "bitbucket"
+)
+
+// Dialects lists every supported dialect.
+var Dialects = []Dialect{GitHub, GitLab, Bitbucket}
+
+// ParseDialect converts a dialect's name, such as "gitlab", into a Dialect.
+func ParseDialect(name string) (Dialect, error) {
+	for _, d := range Dialects {
+		if strings.EqualFold(name, string(d)) {
+			return d, nil
+		}
+	}
+	return "", fmt.Errorf("unknown dialect %q", name)
+}
+
+// OwnerMatchers returns the owner matchers for the kinds of owners the dialect
+// supports.
+func (d Dialect) OwnerMatchers() []OwnerMatcher {
+	switch d {
+	case GitLab:
+		return GitLabOwnerMatchers
+	case Bitbucket:
+		return BitbucketOwnerMatchers
+	default:
+		return DefaultOwnerMatchers
+	}
+}
+
+// StandardLocations returns the locations the dialect's service looks for a
+// CODEOWNERS file in, in the order they're searched.
+func (d Dialect) StandardLocations() []string {
+	switch d {
+	case GitLab:
+		return []string{"CODEOWNERS", "docs/CODEOWNERS", ".gitlab/CODEOWNERS"}
+	case Bitbucket:
+		return []string{".bitbucket/CODEOWNERS"}
+	default:
+		return []string{".github/CODEOWNERS", "CODEOWNERS", "docs/CODEOWNERS"}
+	}
+}
+
+// DetectDialect guesses the dialect of a CODEOWNERS file from its path. Files in
+// .gitlab/ or .bitbucket/ directories belong to those services, and anything
+// else is assumed to be for GitHub, which is the most common.
+func DetectDialect(path string) Dialect {
+	switch filepath.Base(filepath.Dir(path)) {
+	case ".gitlab":
+		return GitLab
+	case ".bitbucket":
+		return Bitbucket
+	default:
+		return GitHub
+	}
+}
+
+// WithDialect sets the dialect a CODEOWNERS file is parsed with. Unless
+// WithOwnerMatchers is also given, the dialect's owner matchers are used. An
+// empty dialect leaves the dialect unchanged.
+func WithDialect(d Dialect) ParseOption {
+	return func(opts *parseOptions) {
+		if d != "" {
+			opts.dialect = d
+		}
+	}
+}
+
+// patternOptions returns the options for compiling the dialect's patterns.
+// GitLab matches patterns with Ruby's File.fnmatch, which supports character
+// classes and brace expansion.
+func (d Dialect) patternOptions() patternOptions {
+	return patternOptions{anyDepth: d == GitLab, charClasses: d == GitLab, braces: d == GitLab}
+}
+
+const (
+	// RoleOwner is the owner type for GitLab roles, such as "@@developer",
+	// which refer to every member of the project with that role.
+	RoleOwner string = "role"
+	// GroupOwner is the owner type for GitLab groups, which may be nested, such
+	// as "@org/group/subgroup".
+	GroupOwner string = "group"
+	// BitbucketGroupOwner is the owner type for Bitbucket groups, such as
+	// "@@reviewers".
+	BitbucketGroupOwner string = "bitbucket-group"
+)
+
+var (
+	roleRegexp           = regexp.MustCompile(`\A@@(developer|maintainer|owner)s?\z`)
+	groupRegexp          = regexp.MustCompile(`\A@([a-zA-Z0-9\-_.]+(?:\/[a-zA-Z0-9\-_.]+)+)\z`)
+	bitbucketGroupRegexp = regexp.MustCompile(`\A@@([a-zA-Z0-9\-_.]+)\z`)
+)
+
+// MatchRoleOwner matches a GitLab role owner, like "@@developer" or
+// "@@maintainers". The value is the singular name of the role. May be provided
+// to WithOwnerMatchers.
+func MatchRoleOwner(s string) (Owner, error) {
+	match := roleRegexp.FindStringSubmatch(s)
+	if match == nil {
+		return Owner{}, ErrNoMatch
+	}
+
+	return Owner{Value: match[1], Type: RoleOwner}, nil
+}
+
+// MatchGroupOwner matches a GitLab group owner, like "@org/group" or
+// "@org/group/subgroup". May be provided to WithOwnerMatchers.
+func MatchGroupOwner(s string) (Owner, error) {
+	match := groupRegexp.FindStringSubmatch(s)
+	if match == nil {
+		return Owner{}, ErrNoMatch
+	}
+
+	return Owner{Value: match[1], Type: GroupOwner}, nil
+}
+
+// MatchBitbucketGroupOwner matches a Bitbucket group owner, like "@@reviewers".
+// May be provided to WithOwnerMatchers.
+func MatchBitbucketGroupOwner(s string) (Owner, error) {
+	match := bitbucketGroupRegexp.FindStringSubmatch(s)
+	if match == nil {
+		return Owner{}, ErrNoMatch
+	}
+
+	return Owner{Value: match[1], Type: BitbucketGroupOwner}, nil
+}
+
+// GitLabOwnerMatchers is the set of owner matchers for the GitLab dialect,
+// which adds roles and nested groups to the email and username matchers.
+var GitLabOwnerMatchers = []OwnerMatcher{
+	OwnerMatchFunc(MatchEmailOwner),
+	OwnerMatchFunc(MatchRoleOwner),
+	OwnerMatchFunc(MatchGroupOwner),
+	OwnerMatchFunc(MatchUsernameOwner),
+}
+
+// BitbucketOwnerMatchers is the set of owner matchers for the Bitbucket
+// dialect, which adds groups to the default matchers.
+var BitbucketOwnerMatchers = []OwnerMatcher{
+	OwnerMatchFunc(MatchEmailOwner),
+	OwnerMatchFunc(MatchBitbucketGroupOwner),
+	OwnerMatchFunc(MatchTeamOwner),
+	OwnerMatchFunc(MatchUsernameOwner),
+}
+
+// Section is a GitLab CODEOWNERS section, started by a header line such as
+// "[Documentation] @docs-team". Rules below the header belong to the section
+// until the next header.
+type Section struct {
+	Name string
+	// Optional sections, whose headers start with "^", don't require approval.
+	Optional bool
+	// Approvals is the number of approvals required, e.g. 2 for "[Name][2]". It's
+	// zero if the header doesn't say.
+	Approvals int
+	// Owners are the section's default owners, which rules in the section that
+	// don't list any owners get.
+	Owners     []Owner
+	LineNumber int
+}
+
+// String returns the section's header line.
+func (s Section) String() string {
+	var b strings.Builder
+	if s.Optional {
+		b.WriteByte('^')
+	}
+	fmt.Fprintf(&b, "[%s]", s.Name)
+	if s.Approvals > 0 {
+		fmt.Fprintf(&b, "[%d]", s.Approvals)
+	}
+	for _, o := range s.Owners {
+		b.WriteByte(' ')
+		b.WriteString(o.String())
+	}
+	return b.String()
+}
+
+var sectionRegexp = regexp.MustCompile(`\A(\^)?\[([^\]]+)\](?:\[(\d+)\])?(.*)\z`)
+
+// isSectionHeader checks whether a line of a GitLab CODEOWNERS file starts a
+// section.
+func isSectionHeader(line string) bool {
+	return strings.HasPrefix(line, "[") || strings.HasPrefix(line, "^[")
+}
+
+// parseSection parses a GitLab section header line.
+func parseSection(line string, opts parseOptions) (*Section, error) {
+	if i := strings.IndexByte(line, '#'); i >= 0 {
+		line = line[:i]
+	}
+	match := sectionRegexp.FindStringSubmatch(strings.TrimSpace(line))
+	if match == nil {
+		return nil, fmt.Errorf("invalid section header")
+	}
+
+	s := &Section{Name: strings.TrimSpace(match[2]), Optional: match[1] != ""}
+	if s.Name == "" {
+		return nil, fmt.Errorf("empty section name")
+	}
+	if match[3] != "" {
+		approvals, err := strconv.Atoi(match[3])
+		if err != nil {
+			return nil, fmt.Errorf("invalid number of approvals %q", match[3])
+		}
+		s.Approvals = approvals
+	}
+	for _, ownerStr := range strings.Fields(match[4]) {
+		owner, err := newOwner(ownerStr, opts.ownerMatchers)
+		if err != nil {
+			return nil, err
+		}
+		s.Owners = append(s.Owners, owner)
+	}
+	return s, nil
+}
+
+// MatchSections finds the last rule that matches the path provided in each
+// GitLab section, ordered by each section's first matching rule. Sections with
+// the same name (ignoring case) are treated as one, and rules before the first
+// section header form a section of their own. Sections with an excluded rule
+// that matches the path are left out. GitLab combines the owners of all of
+// these rules. For rulesets without sections, this returns the same rule as
+// Match, if there is one.
+func (r Ruleset) MatchSections(path string) ([]*Rule, error) {
+	var (
+		names    []string
+		matches  = map[string]*Rule{}
+		excluded = map[string]bool{}
+	)
+	for i := range r {
+		rule := &r[i]
+		name := sectionKey(rule.Section)
+
+		match, err := rule.Match(path)
+		if err != nil {
+			return nil, err
+		}
+		if !match {
+			continue
+		}
+		if rule.Excluded {
+			excluded[name] = true
+			continue
+		}
+		if _, ok := matches[name]; !ok {
+			names = append(names, name)
+		}
+		matches[name] = rule
+	}
+
+	rules := make([]*Rule, 0, len(names))
+	for _, name := range names {
+		if !excluded[name] {
+			rules = append(rules, matches[name])
+		}
+	}
+	return rules, nil
+}
+
+// sectionKey identifies a section by its name, ignoring case. Rules that aren't
+// in a section have an empty key.
+func sectionKey(s *Section) string {
+	if s == nil {
+		return ""
+	}
+	return strings.ToLower(s.Name)
+}
@@ -0,0 +1,206 @@
+package codeowners
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseDialect(t *testing.T) {
+	d, err := ParseDialect("GitLab")
+	require.NoError(t, err)
+	assert.Equal(t, GitLab, d)
+
+	_, err = ParseDialect("gitea")
+	assert.EqualError(t, err, `unknown dialect "gitea"`)
+}
+
+func TestDetectDialect(t *testing.T) {
+	assert.Equal(t, GitHub, DetectDialect("CODEOWNERS"))
+	assert.Equal(t, GitHub, DetectDialect(".github/CODEOWNERS"))
+	assert.Equal(t, GitLab, DetectDialect(".gitlab/CODEOWNERS"))
+	assert.Equal(t, Bitbucket, DetectDialect("repo/.bitbucket/CODEOWNERS"))
+}
+
+func TestStandardLocationsCoverDialects(t *testing.T) {
+	for _, d := range Dialects {
+		prev := -1
+		for _, location := range d.StandardLocations() {
+			i := indexOf(standardLocations, location)
+			require.GreaterOrEqual(t, i, 0, "%s: %s", d, location)
+			assert.Greater(t, i, prev, "%s: %s is out of order", d, location)
+			prev = i
+		}
+	}
+}
+
+func indexOf(list []string, s string) int {
+	for i, item := range list {
+		if item == s {
+			return i
+		}
+	}
+	return -1
+}
+
+func TestGitLabSections(t *testing.T) {
+	file := `# Owners for everything
+* @org/everyone
+
+[Documentation] @docs-team
+docs/
+README.md @alice
+
+^[Optional Review][2] @bob
+*.go
+
+[documentation]
+/docs/internal/ @carol
+`
+	ruleset, err := ParseFile(bytes.NewBufferString(file), WithDialect(GitLab))
+	require.NoError(t, err)
+	require.Len(t, ruleset, 5)
+
+	assert.Nil(t, ruleset[0].Section)
+	docs := ruleset[1].Section
+	require.NotNil(t, docs)
+	assert.Equal(t, "Documentation", docs.Name)
+	assert.Equal(t, 4, docs.LineNumber)
+	assert.Equal(t, []Owner{{Value: "docs-team", Type: UsernameOwner}}, ruleset[1].Owners)
+	assert.Equal(t, []Owner{{Value: "alice", Type: UsernameOwner}}, ruleset[2].Owners)
+
+	optional := ruleset[3].Section
+	require.NotNil(t, optional)
+	assert.True(t, optional.Optional)
+	assert.Equal(t, 2, optional.Approvals)
+	assert.Equal(t, "^[Optional Review][2] @bob", optional.String())
+
+	rules, err := ruleset.MatchSections("docs/internal/main.go")
+	require.NoError(t, err)
+	var lines []int
+	for _, rule := range rules {
+		lines = append(lines, rule.LineNumber)
+	}
+	// Sections with the same name are combined, so /docs/internal/ wins over docs/
+	assert.Equal(t, []int{2, 12, 9}, lines)
+}
+
+func TestGitLabSectionErrors(t *testing.T) {
+	_, err := ParseFile(bytes.NewBufferString("* @a\n[] @b\n"), WithDialect(GitLab))
+	assert.EqualError(t, err, "line 2: invalid section header")
+
+	_, err = ParseFile(bytes.NewBufferString("[Docs] bad!owner\n"), WithDialect(GitLab))
+	assert.Error(t, err)
+
+	// Section headers aren't special in the GitHub dialect
+	_, err = ParseFile(bytes.NewBufferString("[Docs] @a\n"))
+	assert.NoError(t, err)
+}
+
+func TestGitLabMatchesAtAnyDepth(t *testing.T) {
+	ruleset, err := ParseFile(bytes.NewBufferString("docs/README.md @a\n/src/main.go @b\n"), WithDialect(GitLab))
+	require.NoError(t, err)
+
+	for path, want := range map[string]bool{
+		"docs/README.md":     true,
+		"src/docs/README.md": true,
+		"src/main.go":        true,
+		"lib/src/main.go":    false,
+	} {
+		rules, err := ruleset.MatchSections(path)
+		require.NoError(t, err)
+		assert.Equal(t, want, len(rules) > 0, path)
+	}
+
+	github, err := ParseFile(bytes.NewBufferString("docs/README.md @a\n"))
+	require.NoError(t, err)
+	rule, err := github.Match("src/docs/README.md")
+	require.NoError(t, err)
+	assert.Nil(t, rule)
+}
+
+func TestBitbucketGroupOwners(t *testing.T) {
+	ruleset, err := ParseFile(bytes.NewBufferString("* @@reviewers @alice\n"), WithDialect(Bitbucket))
+	require.NoError(t, err)
+	assert.Equal(t, []Owner{{Value: "reviewers", Type: BitbucketGroupOwner}, {Value: "alice", Type: UsernameOwner}}, ruleset[0].Owners)
+	assert.Equal(t, "@@reviewers", ruleset[0].Owners[0].String())
+
+	_, err = ParseFile(bytes.NewBufferString("* @@reviewers\n"))
+	assert.Error(t, err)
+}
+
+func TestGitLabOwners(t *testing.T) {
+	ruleset, err := ParseFile(bytes.NewBufferString("* @@developers @org/group/subgroup @org/team @alice dev@example.com\n"), WithDialect(GitLab))
+	require.NoError(t, err)
+	assert.Equal(t, []Owner{
+		{Value: "developer", Type: RoleOwner},
+		{Value: "org/group/subgroup", Type: GroupOwner},
+		{Value: "org/team", Type: GroupOwner},
+		{Value: "alice", Type: UsernameOwner},
+		{Value: "dev@example.com", Type: EmailOwner},
+	}, ruleset[0].Owners)
+	assert.Equal(t, "@@developer", ruleset[0].Owners[0].String())
+	assert.Equal(t, "@org/group/subgroup", ruleset[0].Owners[1].String())
+
+	// The matchers can be used with other dialects too
+	matchers := []OwnerMatcher{OwnerMatchFunc(MatchRoleOwner), OwnerMatchFunc(MatchGroupOwner)}
+	ruleset, err = ParseFile(bytes.NewBufferString("* @@maintainer @a/b/c\n"), WithOwnerMatchers(matchers))
+	require.NoError(t, err)
+	assert.Equal(t, []Owner{{Value: "maintainer", Type: RoleOwner}, {Value: "a/b/c", Type: GroupOwner}}, ruleset[0].Owners)
+
+	for _, owner := range []string{"@@guest", "@org/group/subgroup", "@@"} {
+		_, err = ParseFile(bytes.NewBufferString("* " + owner + "\n"))
+		assert.Error(t, err, owner)
+	}
+}
+
+func TestGitLabExclusions(t *testing.T) {
+	file := `* @everyone
+!/generated/
+
+[Docs] @docs-team
+*.md
+!/CHANGELOG.md
+`
+	ruleset, err := ParseFile(bytes.NewBufferString(file), WithDialect(GitLab))
+	require.NoError(t, err)
+	require.Len(t, ruleset, 4)
+	assert.True(t, ruleset[1].Excluded)
+	assert.Equal(t, "/generated/", ruleset[1].RawPattern())
+	assert.Empty(t, ruleset[3].Owners)
+	assert.Equal(t, "!/CHANGELOG.md", ruleset[3].String())
+
+	for path, want := range map[string][]int{
+		"main.go":             {1},
+		"generated/api.go":    nil,
+		"README.md":           {1, 5},
+		"generated/README.md": {5},
+		"CHANGELOG.md":        {1},
+		"docs/CHANGELOG.md":   {1, 5},
+	} {
+		rules, err := ruleset.MatchSections(path)
+		require.NoError(t, err)
+		var lines []int
+		for _, rule := range rules {
+			lines = append(lines, rule.LineNumber)
+		}
+		assert.Equal(t, want, lines, path)
+	}
+
+	// Match applies exclusions in the matching rule's section
+	rule, err := ruleset.Match("generated/README.md")
+	require.NoError(t, err)
+	require.NotNil(t, rule)
+	assert.Equal(t, 5, rule.LineNumber)
+	rule, err = ruleset.Match("CHANGELOG.md")
+	require.NoError(t, err)
+	assert.Nil(t, rule)
+
+	_, err = ParseFile(bytes.NewBufferString("!/docs/ @a\n"), WithDialect(GitLab))
+	assert.EqualError(t, err, "line 1: excluded patterns can't have owners")
+
+	_, err = ParseFile(bytes.NewBufferString("!/docs/\n"))
+	assert.Error(t, err)
+}
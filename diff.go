@@ -0,0 +1,124 @@
+package codeowners
+
+import (
+	"sort"
+	"strings"
+)
+
+const (
+	// OwnershipChanged is the transition kind for paths whose owners changed.
+	OwnershipChanged string = "changed"
+	// OwnershipGained is the transition kind for unowned paths that gained
+	// owners.
+	OwnershipGained string = "gained"
+	// OwnershipLost is the transition kind for owned paths that became unowned.
+	OwnershipLost string = "lost"
+)
+
+// OwnershipTransition is a group of paths whose ownership changed in the same
+// way between two rulesets, e.g. every path that moved from one team to
+// another.
+type OwnershipTransition struct {
+	// Kind will be one of 'changed', 'gained', or 'lost'.
+	Kind string
+	// OldOwners is empty for paths that were unowned.
+	OldOwners []Owner
+	// NewOwners is empty for paths that became unowned.
+	NewOwners []Owner
+	// Paths lists the affected paths in sorted order.
+	Paths []string
+}
+
+// DiffOwnership determines the owners of each of the paths provided under two
+// rulesets (e.g. a CODEOWNERS file before and after an edit), returning the
+// paths whose owners differ grouped by old and new owners. A path is
+// considered unowned if no rule matches it or the matching rule has no owners.
+// For GitLab rulesets with sections, the owners from every section are
+// combined, as in MatchSections.
+// The order owners are listed in doesn't matter. Transitions are sorted by
+// kind, then by owners.
+func DiffOwnership(oldRuleset, newRuleset Ruleset, paths []string) ([]OwnershipTransition, error) {
+	transitions := map[string]*OwnershipTransition{}
+	for _, path := range paths {
+		oldOwners, err := oldRuleset.owners(path)
+		if err != nil {
+			return nil, err
+		}
+		newOwners, err := newRuleset.owners(path)
+		if err != nil {
+			return nil, err
+		}
+
+		oldKey, newKey := ownersKey(oldOwners), ownersKey(newOwners)
+		if oldKey == newKey {
+			continue
+		}
+
+		kind := OwnershipChanged
+		switch {
+		case len(oldOwners) == 0:
+			kind = OwnershipGained
+		case len(newOwners) == 0:
+			kind = OwnershipLost
+		}
+
+		key := oldKey + "\x00" + newKey
+		transition, ok := transitions[key]
+		if !ok {
+			transition = &OwnershipTransition{Kind: kind, OldOwners: oldOwners, NewOwners: newOwners}
+			transitions[key] = transition
+		}
+		transition.Paths = append(transition.Paths, path)
+	}
+
+	kindOrder := map[string]int{OwnershipChanged: 0, OwnershipGained: 1, OwnershipLost: 2}
+	result := make([]OwnershipTransition, 0, len(transitions))
+	for _, transition := range transitions {
+		sort.Strings(transition.Paths)
+		result = append(result, *transition)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		a, b := result[i], result[j]
+		if a.Kind != b.Kind {
+			return kindOrder[a.Kind] < kindOrder[b.Kind]
+		}
+		if keyA, keyB := ownersKey(a.OldOwners), ownersKey(b.OldOwners); keyA != keyB {
+			return keyA < keyB
+		}
+		return ownersKey(a.NewOwners) < ownersKey(b.NewOwners)
+	})
+	return result, nil
+}
+
+// owners returns the owners of a path, or nil if the path is unowned. The
+// owners of the matching rule in each GitLab section are combined, as GitLab
+// does.
+func (r Ruleset) owners(path string) ([]Owner, error) {
+	rules, err := r.MatchSections(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var owners []Owner
+	seen := map[string]bool{}
+	for _, rule := range rules {
+		for _, o := range rule.Owners {
+			if !seen[o.String()] {
+				seen[o.String()] = true
+				owners = append(owners, o)
+			}
+		}
+	}
+	return owners, nil
+}
+
+// ownersKey returns a string that's equal for sets of owners with the same
+// members, regardless of their order.
+func ownersKey(owners []Owner) string {
+	keys := make([]string, len(owners))
+	for i, o := range owners {
+		keys[i] = o.String()
+	}
+	sort.Strings(keys)
+	return strings.Join(keys, " ")
+}
@@ -0,0 +1,109 @@
+package codeowners
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDiffOwnership(t *testing.T) {
+	oldRuleset := mustParseFile(t, strings.Join([]string{
+		"*         @org/everyone",
+		"/docs/    @org/docs",
+		"/legacy/  @org/legacy",
+		"/vendor/",
+	}, "\n"))
+	newRuleset := mustParseFile(t, strings.Join([]string{
+		"*              @org/everyone",
+		"/docs/         @org/writers",
+		"/docs/api/     @org/docs @org/api",
+		"/legacy/",
+		"/vendor/       @org/deps",
+		"/README.md     @org/everyone",
+	}, "\n"))
+
+	paths := []string{
+		"README.md",
+		"docs/guide.md",
+		"docs/api/a.md",
+		"docs/index.md",
+		"legacy/old.go",
+		"main.go",
+		"vendor/lib.go",
+	}
+
+	transitions, err := DiffOwnership(oldRuleset, newRuleset, paths)
+	require.NoError(t, err)
+	assert.Equal(t, []OwnershipTransition{
+		{
+			Kind:      OwnershipChanged,
+			OldOwners: []Owner{{Value: "org/docs", Type: TeamOwner}},
+			NewOwners: []Owner{{Value: "org/docs", Type: TeamOwner}, {Value: "org/api", Type: TeamOwner}},
+			Paths:     []string{"docs/api/a.md"},
+		},
+		{
+			Kind:      OwnershipChanged,
+			OldOwners: []Owner{{Value: "org/docs", Type: TeamOwner}},
+			NewOwners: []Owner{{Value: "org/writers", Type: TeamOwner}},
+			Paths:     []string{"docs/guide.md", "docs/index.md"},
+		},
+		{
+			Kind:      OwnershipGained,
+			NewOwners: []Owner{{Value: "org/deps", Type: TeamOwner}},
+			Paths:     []string{"vendor/lib.go"},
+		},
+		{
+			Kind:      OwnershipLost,
+			OldOwners: []Owner{{Value: "org/legacy", Type: TeamOwner}},
+			Paths:     []string{"legacy/old.go"},
+		},
+	}, transitions)
+}
+
+// Reordering the owners of a rule doesn't change who owns the files it matches
+func TestDiffOwnershipIgnoresOwnerOrder(t *testing.T) {
+	oldRuleset := mustParseFile(t, "* @a @b")
+	newRuleset := mustParseFile(t, "* @b @a")
+
+	transitions, err := DiffOwnership(oldRuleset, newRuleset, []string{"main.go"})
+	require.NoError(t, err)
+	assert.Empty(t, transitions)
+}
+
+// GitLab combines the owners from each section's matching rule
+func TestDiffOwnershipSections(t *testing.T) {
+	parse := func(contents string) Ruleset {
+		ruleset, err := ParseFile(strings.NewReader(contents), WithDialect(GitLab))
+		require.NoError(t, err)
+		return ruleset
+	}
+	group := func(name string) Owner { return Owner{Value: name, Type: GroupOwner} }
+	oldRuleset := parse("* @org/everyone\n[Docs]\n/docs/ @org/docs\n")
+	newRuleset := parse("* @org/everyone\n[Docs]\n/docs/ @org/writers\n[Security]\n/src/ @org/security\n")
+
+	transitions, err := DiffOwnership(oldRuleset, newRuleset, []string{"README.md", "docs/guide.md", "src/main.go"})
+	require.NoError(t, err)
+	assert.Equal(t, []OwnershipTransition{
+		{
+			Kind:      OwnershipChanged,
+			OldOwners: []Owner{group("org/everyone"), group("org/docs")},
+			NewOwners: []Owner{group("org/everyone"), group("org/writers")},
+			Paths:     []string{"docs/guide.md"},
+		},
+		{
+			Kind:      OwnershipChanged,
+			OldOwners: []Owner{group("org/everyone")},
+			NewOwners: []Owner{group("org/everyone"), group("org/security")},
+			Paths:     []string{"src/main.go"},
+		},
+	}, transitions)
+}
+
+func mustParseFile(t *testing.T, contents string) Ruleset {
+	t.Helper()
+	ruleset, err := ParseFile(strings.NewReader(contents))
+	require.NoError(t, err)
+	return ruleset
+}
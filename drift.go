@@ -0,0 +1,203 @@
+package codeowners
+
+import (
+	"sort"
+	"strings"
+	"time"
+)
+
+// OwnershipDrift describes a directory whose owners have stopped working on it,
+// as none of its recent commits were made by them.
+type OwnershipDrift struct {
+	// Dir is the directory, relative to the root of the repository. An empty
+	// string refers to the root itself.
+	Dir string
+	// Owners are the owners the ruleset assigns to the directory's files.
+	Owners []Owner
+	// Paths lists the files in the directory with those owners.
+	Paths []string
+	// Commits is the number of recent commits that were considered, none of
+	// which were made by the owners.
+	Commits int
+	// Authors are the owners representing the authors of those commits, sorted
+	// by descending number of commits.
+	Authors []AuthorCommits
+	// LastOwnerCommit is when an owner last authored a commit to the directory,
+	// or the zero time if they never have.
+	LastOwnerCommit time.Time
+}
+
+// AuthorCommits counts the commits made by an author.
+type AuthorCommits struct {
+	Owner   Owner
+	Commits int
+}
+
+// DriftOptions configure DetectDrift.
+type DriftOptions struct {
+	// Authors maps commit authors to owners. See AuthorMap.Owner for how
+	// authors that aren't in the map are handled.
+	Authors AuthorMap
+	// Teams maps teams (as returned by Owner.String, e.g. "@org/team") to their
+	// members, so commits made by a member count as being made by the team.
+	Teams map[string][]Owner
+	// Commits is the number of recent commits to each directory to look at. It
+	// defaults to 20.
+	Commits int
+	// MinCommits is the number of recent commits a directory needs before it's
+	// reported, so that rarely changed directories aren't flagged. It defaults
+	// to 5.
+	MinCommits int
+	// Depth limits how deep directories go, so that files in deeper directories
+	// are grouped with their ancestor at this depth. Zero means no limit.
+	Depth int
+}
+
+// DetectDrift finds directories where the owners assigned by the ruleset have
+// made none of the most recent commits, suggesting the ownership is stale.
+// Files is the full list of files in the repository, and commits is its
+// history, newest first (see Repository.Log). Files in the same directory with
+// different owners are considered separately, and unowned files are ignored.
+//
+// Results are ranked by the number of commits considered, then by the share of
+// those commits made by the most active author, so the directories where the
+// owners have been most clearly replaced come first.
+func DetectDrift(ruleset Ruleset, files []string, commits []Commit, opts DriftOptions) ([]OwnershipDrift, error) {
+	if opts.Commits <= 0 {
+		opts.Commits = 20
+	}
+	if opts.MinCommits <= 0 {
+		opts.MinCommits = 5
+	}
+
+	type group struct {
+		drift   OwnershipDrift
+		authors map[string]*AuthorCommits
+		// owned is set once an owner has made one of the recent commits
+		owned bool
+	}
+	groups := map[string]*group{}
+	groupOf := map[string]*group{}
+	for _, file := range files {
+		owners, err := ruleset.owners(file)
+		if err != nil {
+			return nil, err
+		}
+		if len(owners) == 0 {
+			continue
+		}
+
+		dir := truncateDir(parentDir(file), opts.Depth)
+		key := dir + "\x00" + ownersKey(owners)
+		g, ok := groups[key]
+		if !ok {
+			g = &group{
+				drift:   OwnershipDrift{Dir: dir, Owners: owners},
+				authors: map[string]*AuthorCommits{},
+			}
+			groups[key] = g
+		}
+		g.drift.Paths = append(g.drift.Paths, file)
+		groupOf[file] = g
+	}
+
+	for _, commit := range commits {
+		author, ok := opts.Authors.Owner(commit.AuthorEmail)
+		if !ok {
+			continue
+		}
+
+		seen := map[*group]bool{}
+		for _, file := range commit.Files {
+			g := groupOf[file]
+			if g == nil || seen[g] {
+				continue
+			}
+			seen[g] = true
+
+			isOwner := ownedBy(g.drift.Owners, author, opts.Teams)
+			if isOwner && g.drift.LastOwnerCommit.IsZero() {
+				g.drift.LastOwnerCommit = commit.AuthorTime
+			}
+			if g.owned || g.drift.Commits == opts.Commits {
+				continue
+			}
+			if isOwner {
+				g.owned = true
+				continue
+			}
+
+			g.drift.Commits++
+			counts, ok := g.authors[author.String()]
+			if !ok {
+				counts = &AuthorCommits{Owner: author}
+				g.authors[author.String()] = counts
+			}
+			counts.Commits++
+		}
+	}
+
+	var drifts []OwnershipDrift
+	for _, g := range groups {
+		if g.owned || g.drift.Commits < opts.MinCommits {
+			continue
+		}
+		for _, counts := range g.authors {
+			g.drift.Authors = append(g.drift.Authors, *counts)
+		}
+		sort.Slice(g.drift.Authors, func(i, j int) bool {
+			a, b := g.drift.Authors[i], g.drift.Authors[j]
+			if a.Commits != b.Commits {
+				return a.Commits > b.Commits
+			}
+			return a.Owner.String() < b.Owner.String()
+		})
+		sort.Strings(g.drift.Paths)
+		drifts = append(drifts, g.drift)
+	}
+
+	sort.Slice(drifts, func(i, j int) bool {
+		a, b := drifts[i], drifts[j]
+		if a.Commits != b.Commits {
+			return a.Commits > b.Commits
+		}
+		// With equal commit counts, the top author's share is their count
+		if a.Authors[0].Commits != b.Authors[0].Commits {
+			return a.Authors[0].Commits > b.Authors[0].Commits
+		}
+		if a.Dir != b.Dir {
+			return a.Dir < b.Dir
+		}
+		return ownersKey(a.Owners) < ownersKey(b.Owners)
+	})
+	return drifts, nil
+}
+
+// ownedBy checks whether the author is one of the owners, or a member of one
+// of the owning teams.
+func ownedBy(owners []Owner, author Owner, teams map[string][]Owner) bool {
+	for _, owner := range owners {
+		if strings.EqualFold(owner.String(), author.String()) {
+			return true
+		}
+		for _, member := range teams[owner.String()] {
+			if strings.EqualFold(member.String(), author.String()) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// truncateDir returns the ancestor of a directory at the depth provided, or
+// the directory itself if it's shallower. A depth of zero means no limit.
+func truncateDir(dir string, depth int) string {
+	if depth <= 0 || dir == "" {
+		return dir
+	}
+	parts := strings.SplitN(dir, "/", depth+1)
+	if len(parts) > depth {
+		parts = parts[:depth]
+	}
+	return strings.Join(parts, "/")
+}
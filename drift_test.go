@@ -0,0 +1,96 @@
+package codeowners
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDetectDrift(t *testing.T) {
+	start := time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC)
+	var commits []Commit
+	commit := func(email string, files ...string) {
+		// Commits are listed newest first, so each one is older than the last
+		commits = append(commits, Commit{
+			AuthorEmail: email,
+			AuthorTime:  start.Add(-time.Duration(len(commits)) * 24 * time.Hour),
+			Files:       files,
+		})
+	}
+
+	ruleset := mustParseFile(t, "/api/ @org/api\n/web/ @alice\n/docs/ docs@example.com\n/tools/ @org/platform")
+	files := []string{"api/server.go", "api/handlers/users.go", "web/app.js", "docs/README.md", "tools/lint.sh", "README.md"}
+
+	for i := 0; i < 4; i++ {
+		commit("bob@example.com", "api/server.go", "web/app.js")
+	}
+	commit("carol@example.com", "api/handlers/users.go")
+	commit("dave@example.com", "api/server.go")
+	commit("alice@example.com", "api/server.go", "web/app.js")
+	commit("bob@example.com", "docs/README.md")
+	commit("docs@example.com", "docs/README.md")
+	for i := 0; i < 5; i++ {
+		commit("erin@example.com", "tools/lint.sh", "README.md")
+	}
+	commit("49699333+dependabot[bot]@users.noreply.github.com", "tools/lint.sh")
+
+	authors := AuthorMap{"alice@example.com": {Value: "alice", Type: UsernameOwner}}
+	teams := map[string][]Owner{"@org/api": {{Value: "Alice", Type: UsernameOwner}}}
+
+	drifts, err := DetectDrift(ruleset, files, commits, DriftOptions{
+		Authors:    authors,
+		Teams:      teams,
+		Commits:    5,
+		MinCommits: 3,
+	})
+	require.NoError(t, err)
+
+	var dirs []string
+	for _, d := range drifts {
+		dirs = append(dirs, d.Dir)
+	}
+	// Owners made recent commits to web and docs, and api/handlers has too few
+	// commits. Erin made all of the commits to tools, so it ranks first.
+	assert.Equal(t, []string{"tools", "api"}, dirs)
+
+	api := drifts[1]
+	assert.Equal(t, 5, api.Commits)
+	assert.Equal(t, []AuthorCommits{
+		{Owner: Owner{Value: "bob@example.com", Type: EmailOwner}, Commits: 4},
+		{Owner: Owner{Value: "dave@example.com", Type: EmailOwner}, Commits: 1},
+	}, api.Authors)
+	// Alice is a member of the team, but her commit is outside the window
+	assert.Equal(t, start.Add(-6*24*time.Hour), api.LastOwnerCommit)
+
+	// Bot commits are skipped
+	tools := drifts[0]
+	assert.Equal(t, 5, tools.Commits)
+	assert.True(t, tools.LastOwnerCommit.IsZero())
+
+	// Grouping by top-level directory merges api/handlers into api
+	drifts, err = DetectDrift(ruleset, files, commits, DriftOptions{
+		Authors:    authors,
+		Teams:      teams,
+		Commits:    5,
+		MinCommits: 3,
+		Depth:      1,
+	})
+	require.NoError(t, err)
+	require.Len(t, drifts, 2)
+	assert.Equal(t, "api", drifts[1].Dir)
+	assert.Equal(t, []string{"api/handlers/users.go", "api/server.go"}, drifts[1].Paths)
+	assert.Equal(t, []AuthorCommits{
+		{Owner: Owner{Value: "bob@example.com", Type: EmailOwner}, Commits: 4},
+		{Owner: Owner{Value: "carol@example.com", Type: EmailOwner}, Commits: 1},
+	}, drifts[1].Authors)
+}
+
+func TestTruncateDir(t *testing.T) {
+	assert.Equal(t, "a/b/c", truncateDir("a/b/c", 0))
+	assert.Equal(t, "a", truncateDir("a/b/c", 1))
+	assert.Equal(t, "a/b", truncateDir("a/b/c", 2))
+	assert.Equal(t, "a/b/c", truncateDir("a/b/c", 5))
+	assert.Equal(t, "", truncateDir("", 2))
+}
@@ -0,0 +1,123 @@
+package codeowners
+
+import (
+	"bytes"
+	"fmt"
+	"path"
+	"sort"
+	"strings"
+)
+
+// DirectoryOwners assigns a set of owners to a directory and everything in it.
+type DirectoryOwners struct {
+	// Dir is the path to the directory relative to the root of the repository,
+	// using forward slashes. An empty string refers to the root itself.
+	Dir string
+	// Owners may be empty to leave the directory unowned, overriding the owners
+	// of its parent.
+	Owners []Owner
+}
+
+// generateProbe is a file name used to check the ownership of directories in
+// generated rulesets. It just needs to be unlikely to clash with a directory.
+const generateProbe = ".codeowners-generate-probe"
+
+// Generate builds a ruleset from a mapping of directories to owners, such as
+// one exported from a service catalog. Parent directories are ordered before
+// their children so the more specific rule wins, and directories with the same
+// owners as their closest listed parent are left out as they'd be redundant.
+//
+// The ruleset is checked by writing it out in the CODEOWNERS format and parsing
+// it again with ParseFile, so an error is returned if the result wouldn't mean
+// the same thing once saved (e.g. because a directory name can't be expressed
+// as a pattern).
+func Generate(mapping []DirectoryOwners) (Ruleset, error) {
+	owners := make(map[string][]Owner, len(mapping))
+	dirs := make([]string, 0, len(mapping))
+	for _, entry := range mapping {
+		dir := cleanDir(entry.Dir)
+		if _, ok := owners[dir]; ok {
+			return nil, fmt.Errorf("directory %q is listed more than once", entry.Dir)
+		}
+		owners[dir] = entry.Owners
+		dirs = append(dirs, dir)
+	}
+
+	// A directory sorts after every directory it's inside, as they're prefixes
+	sort.Strings(dirs)
+
+	ruleset := Ruleset{}
+	for _, dir := range dirs {
+		inherited := []Owner(nil)
+		for parent := dir; parent != ""; {
+			parent = parentDir(parent)
+			if parentOwners, ok := owners[parent]; ok {
+				inherited = parentOwners
+				break
+			}
+		}
+		if ownersKey(owners[dir]) == ownersKey(inherited) {
+			continue
+		}
+
+		pattern := "*"
+		if dir != "" {
+			if err := checkPatternPath(dir); err != nil {
+				return nil, fmt.Errorf("directory %w", err)
+			}
+			pattern = "/" + escapePattern(dir) + "/"
+		}
+		rule, err := NewRule(pattern, owners[dir])
+		if err != nil {
+			return nil, fmt.Errorf("directory %q: %w", dir, err)
+		}
+		ruleset = append(ruleset, rule)
+	}
+
+	if err := checkRoundTrip(ruleset, owners); err != nil {
+		return nil, err
+	}
+	return ruleset, nil
+}
+
+// checkRoundTrip writes out and re-parses a generated ruleset, checking the
+// rules are unchanged and that every directory has the expected owners.
+func checkRoundTrip(ruleset Ruleset, owners map[string][]Owner) error {
+	var buf bytes.Buffer
+	if err := WriteFile(&buf, ruleset); err != nil {
+		return err
+	}
+	parsed, err := ParseFile(&buf)
+	if err != nil {
+		return fmt.Errorf("generated CODEOWNERS file doesn't parse: %w", err)
+	}
+
+	if len(parsed) != len(ruleset) {
+		return fmt.Errorf("generated CODEOWNERS file has %d rules, expected %d", len(parsed), len(ruleset))
+	}
+	for i := range ruleset {
+		if parsed[i].RawPattern() != ruleset[i].RawPattern() || ownersKey(parsed[i].Owners) != ownersKey(ruleset[i].Owners) {
+			return fmt.Errorf("generated rule %q was parsed as %q", ruleset[i].String(), parsed[i].String())
+		}
+	}
+
+	for dir, expected := range owners {
+		probe := path.Join(dir, generateProbe)
+		actual, err := parsed.owners(probe)
+		if err != nil {
+			return err
+		}
+		if ownersKey(actual) != ownersKey(expected) {
+			return fmt.Errorf("directory %q is owned by [%s] in the generated CODEOWNERS file, expected [%s]",
+				dir, ownersKey(actual), ownersKey(expected))
+		}
+	}
+	return nil
+}
+
+// cleanDir normalises a directory path relative to the root, returning an empty
+// string for the root itself.
+func cleanDir(dir string) string {
+	dir = path.Clean("/" + strings.TrimPrefix(dir, "./"))
+	return strings.TrimPrefix(dir, "/")
+}
@@ -0,0 +1,57 @@
+package codeowners
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGenerate(t *testing.T) {
+	everyone := Owner{Value: "org/everyone", Type: TeamOwner}
+	platform := Owner{Value: "org/platform", Type: TeamOwner}
+	docs := Owner{Value: "org/docs", Type: TeamOwner}
+
+	ruleset, err := Generate([]DirectoryOwners{
+		{Dir: "services/api/internal", Owners: []Owner{platform}},
+		{Dir: "", Owners: []Owner{everyone}},
+		{Dir: "services/api", Owners: []Owner{platform}},
+		{Dir: "./docs/", Owners: []Owner{docs}},
+		{Dir: "docs/generated", Owners: nil},
+		{Dir: "services/web app", Owners: []Owner{everyone}},
+	})
+	require.NoError(t, err)
+
+	patterns := make([]string, len(ruleset))
+	for i, rule := range ruleset {
+		patterns[i] = rule.String()
+	}
+	assert.Equal(t, []string{
+		"* @org/everyone",
+		"/docs/ @org/docs",
+		"/docs/generated/",
+		"/services/api/ @org/platform",
+	}, patterns)
+
+	rule, err := ruleset.Match("services/api/internal/server.go")
+	require.NoError(t, err)
+	assert.Equal(t, []Owner{platform}, rule.Owners)
+}
+
+func TestGenerateErrors(t *testing.T) {
+	owners := []Owner{{Value: "org/everyone", Type: TeamOwner}}
+
+	_, err := Generate([]DirectoryOwners{
+		{Dir: "src", Owners: owners},
+		{Dir: "./src/", Owners: owners},
+	})
+	assert.EqualError(t, err, `directory "./src/" is listed more than once`)
+
+	// Patterns can't contain newlines, so this can't be represented
+	_, err = Generate([]DirectoryOwners{{Dir: "src\nlib", Owners: owners}})
+	assert.Error(t, err)
+
+	// GitHub starts a comment at "#", even if it's escaped
+	_, err = Generate([]DirectoryOwners{{Dir: "pkg/c#", Owners: owners}})
+	assert.EqualError(t, err, `directory "pkg/c#" contains "#", which can't be used in CODEOWNERS patterns`)
+}
@@ -0,0 +1,348 @@
+package codeowners
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// ErrNotRepository is returned by FindRepository when the path provided isn't
+// inside a git work tree.
+var ErrNotRepository = errors.New("not a git repository")
+
+// Repository is a git repository on the local file system. The work tree and
+// index are read directly from the .git directory, so no git binary is
+// required for them. Reading history, such as files at other revisions, relies
+// on the git binary.
+type Repository struct {
+	// Root is the path to the top level of the repository's work tree.
+	Root string
+	// GitDir is the path to the git directory for the work tree. For linked
+	// worktrees, this is the worktree-specific directory under .git/worktrees.
+	GitDir string
+	// CommonDir is the path to the git directory shared by all of the
+	// repository's worktrees, which holds objects, refs, and config.
+	CommonDir string
+}
+
+// FindRepository finds the git repository whose work tree contains the path
+// provided, searching parent directories as git does. Both .git directories
+// and .git files (as used by worktrees and submodules) are supported. If the
+// path isn't inside a git work tree, ErrNotRepository is returned.
+func FindRepository(path string) (*Repository, error) {
+	dir, err := filepath.Abs(path)
+	if err != nil {
+		return nil, err
+	}
+
+	for {
+		gitDir, err := resolveGitDir(filepath.Join(dir, ".git"))
+		if err != nil {
+			return nil, err
+		}
+		if gitDir != "" {
+			commonDir, err := resolveCommonDir(gitDir)
+			if err != nil {
+				return nil, err
+			}
+			return &Repository{Root: dir, GitDir: gitDir, CommonDir: commonDir}, nil
+		}
+
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return nil, ErrNotRepository
+		}
+		dir = parent
+	}
+}
+
+// resolveGitDir returns the git directory referred to by a .git entry in a
+// work tree, which is either the git directory itself or a file containing a
+// "gitdir: <path>" line. An empty string is returned if there's no git
+// directory at the path.
+func resolveGitDir(dotGit string) (string, error) {
+	info, err := os.Stat(dotGit)
+	if os.IsNotExist(err) {
+		return "", nil
+	} else if err != nil {
+		return "", err
+	}
+
+	if info.IsDir() {
+		if !fileExists(filepath.Join(dotGit, "HEAD")) {
+			return "", nil
+		}
+		return dotGit, nil
+	}
+
+	contents, err := os.ReadFile(dotGit)
+	if err != nil {
+		return "", err
+	}
+	line := strings.TrimSpace(string(contents))
+	if !strings.HasPrefix(line, "gitdir:") {
+		return "", fmt.Errorf("invalid gitfile format: %s", dotGit)
+	}
+
+	gitDir := strings.TrimSpace(strings.TrimPrefix(line, "gitdir:"))
+	if !filepath.IsAbs(gitDir) {
+		gitDir = filepath.Join(filepath.Dir(dotGit), gitDir)
+	}
+	return filepath.Clean(gitDir), nil
+}
+
+// resolveCommonDir returns the git directory shared between worktrees. Linked
+// worktrees point to it with a commondir file; otherwise it's the git
+// directory itself.
+func resolveCommonDir(gitDir string) (string, error) {
+	contents, err := os.ReadFile(filepath.Join(gitDir, "commondir"))
+	if os.IsNotExist(err) {
+		return gitDir, nil
+	} else if err != nil {
+		return "", err
+	}
+
+	commonDir := strings.TrimSpace(string(contents))
+	if !filepath.IsAbs(commonDir) {
+		commonDir = filepath.Join(gitDir, commonDir)
+	}
+	return filepath.Clean(commonDir), nil
+}
+
+// TrackedFiles lists the paths of the files in the repository's index, relative
+// to the root of the work tree and using forward slashes as separators. This
+// is equivalent to running `git ls-files --full-name` from the root.
+func (r *Repository) TrackedFiles() ([]string, error) {
+	data, err := os.ReadFile(filepath.Join(r.GitDir, "index"))
+	if os.IsNotExist(err) {
+		// A repository with nothing staged yet has no index
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	hashSize, err := r.hashSize()
+	if err != nil {
+		return nil, err
+	}
+
+	entries, err := readIndex(data, hashSize)
+	if err != nil {
+		return nil, fmt.Errorf("reading git index: %w", err)
+	}
+
+	files := make([]string, 0, len(entries))
+	for _, entry := range entries {
+		// Unmerged files have an entry for each conflict stage, but they're
+		// sorted by path so duplicates are always adjacent
+		if len(files) > 0 && files[len(files)-1] == entry.path {
+			continue
+		}
+		files = append(files, entry.path)
+	}
+	return files, nil
+}
+
+// hashSize returns the size in bytes of the object IDs used by the repository,
+// which depends on the hash function it was created with.
+func (r *Repository) hashSize() (int, error) {
+	format, err := r.configValue("extensions", "objectformat")
+	if err != nil {
+		return 0, err
+	}
+
+	switch strings.ToLower(format) {
+	case "", "sha1":
+		return 20, nil
+	case "sha256":
+		return 32, nil
+	default:
+		return 0, fmt.Errorf("unsupported object format %q", format)
+	}
+}
+
+// configValue reads a value from the repository's config file.
+func (r *Repository) configValue(section, key string) (string, error) {
+	return readConfigValue(filepath.Join(r.CommonDir, "config"), section, key)
+}
+
+// readConfigValue reads a value from a git config file, returning an empty
+// string if the file or value doesn't exist. Only the subset of the config
+// format needed for simple section.key lookups is supported; includes and
+// subsections are ignored.
+func readConfigValue(path, section, key string) (string, error) {
+	f, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return "", nil
+	} else if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	value := ""
+	currentSection := ""
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if len(line) == 0 || line[0] == '#' || line[0] == ';' {
+			continue
+		}
+
+		if line[0] == '[' {
+			currentSection = strings.ToLower(strings.Trim(line, "[]"))
+			continue
+		}
+
+		if currentSection != section {
+			continue
+		}
+		name, val, _ := strings.Cut(line, "=")
+		if strings.EqualFold(strings.TrimSpace(name), key) {
+			// Later values override earlier ones
+			value = strings.Trim(strings.TrimSpace(val), `"`)
+		}
+	}
+	return value, scanner.Err()
+}
+
+// indexEntry is a single entry in a git index file.
+type indexEntry struct {
+	path  string
+	mode  uint32
+	stage int
+}
+
+const (
+	indexHeaderSize   = 12
+	indexStatSize     = 40
+	indexFlagExtended = 0x4000
+	indexFlagStage    = 0x3000
+	modeTypeMask      = 0o170000
+	modeDirectory     = 0o040000
+)
+
+// readIndex parses the contents of a git index file, supporting index format
+// versions 2, 3, and 4. The format is documented in git's
+// Documentation/gitformat-index.txt.
+func readIndex(data []byte, hashSize int) ([]indexEntry, error) {
+	if len(data) < indexHeaderSize || string(data[:4]) != "DIRC" {
+		return nil, fmt.Errorf("invalid index signature")
+	}
+	version := binary.BigEndian.Uint32(data[4:8])
+	if version < 2 || version > 4 {
+		return nil, fmt.Errorf("unsupported index version %d", version)
+	}
+	count := binary.BigEndian.Uint32(data[8:12])
+
+	// Don't trust the entry count for preallocation, as it may be corrupt
+	capacity := int(count)
+	if maxEntries := len(data) / (indexStatSize + hashSize); capacity > maxEntries {
+		capacity = maxEntries
+	}
+	entries := make([]indexEntry, 0, capacity)
+	offset := indexHeaderSize
+	prevPath := ""
+	for i := uint32(0); i < count; i++ {
+		start := offset
+		fixedSize := indexStatSize + hashSize + 2
+		if offset+fixedSize > len(data) {
+			return nil, fmt.Errorf("index truncated")
+		}
+
+		mode := binary.BigEndian.Uint32(data[offset+24 : offset+28])
+		flags := binary.BigEndian.Uint16(data[offset+indexStatSize+hashSize : offset+fixedSize])
+		offset += fixedSize
+
+		if flags&indexFlagExtended != 0 {
+			if version < 3 {
+				return nil, fmt.Errorf("extended flags in version %d index", version)
+			}
+			// Extended flags (skip-worktree, intent-to-add) don't affect listing
+			offset += 2
+		}
+
+		var path string
+		if version == 4 {
+			// Paths are prefix-compressed relative to the previous entry
+			strip, n := decodeIndexVarint(data[offset:])
+			if n == 0 || strip > len(prevPath) {
+				return nil, fmt.Errorf("invalid path compression in entry %d", i)
+			}
+			offset += n
+
+			end := bytes.IndexByte(data[offset:], 0)
+			if end < 0 {
+				return nil, fmt.Errorf("index truncated")
+			}
+			path = prevPath[:len(prevPath)-strip] + string(data[offset:offset+end])
+			offset += end + 1
+		} else {
+			end := bytes.IndexByte(data[offset:], 0)
+			if end < 0 {
+				return nil, fmt.Errorf("index truncated")
+			}
+			path = string(data[offset : offset+end])
+
+			// Entries are NUL-padded to a multiple of eight bytes
+			entryLen := offset + end - start
+			offset = start + (entryLen+8)&^7
+		}
+
+		if mode&modeTypeMask == modeDirectory {
+			return nil, fmt.Errorf("sparse indexes are not supported")
+		}
+
+		entries = append(entries, indexEntry{
+			path:  path,
+			mode:  mode,
+			stage: int(flags&indexFlagStage) >> 12,
+		})
+		prevPath = path
+	}
+
+	if err := checkIndexExtensions(data[offset:], hashSize); err != nil {
+		return nil, err
+	}
+	return entries, nil
+}
+
+// checkIndexExtensions rejects indexes using extensions that change which
+// entries are present, which we don't support.
+func checkIndexExtensions(data []byte, hashSize int) error {
+	for len(data) >= 8+hashSize {
+		signature := string(data[:4])
+		size := int(binary.BigEndian.Uint32(data[4:8]))
+		if signature == "link" {
+			return fmt.Errorf("split indexes are not supported")
+		}
+		if 8+size > len(data) {
+			break
+		}
+		data = data[8+size:]
+	}
+	return nil
+}
+
+// decodeIndexVarint decodes the variable-length integers used for path
+// compression in version 4 indexes. It returns the value and the number of
+// bytes consumed, which is zero if the input is truncated.
+func decodeIndexVarint(data []byte) (int, int) {
+	if len(data) == 0 {
+		return 0, 0
+	}
+	val := int(data[0] & 0x7f)
+	n := 1
+	for data[n-1]&0x80 != 0 {
+		if n >= len(data) {
+			return 0, 0
+		}
+		val = ((val + 1) << 7) | int(data[n]&0x7f)
+		n++
+	}
+	return val, n
+}
@@ -0,0 +1,150 @@
+package codeowners
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFindRepository(t *testing.T) {
+	root := t.TempDir()
+	gitDir := filepath.Join(root, ".git")
+	writeTestFile(t, filepath.Join(gitDir, "HEAD"), "ref: refs/heads/main\n")
+	require.NoError(t, os.MkdirAll(filepath.Join(root, "a", "b"), 0o755))
+
+	for _, path := range []string{root, filepath.Join(root, "a"), filepath.Join(root, "a", "b")} {
+		repo, err := FindRepository(path)
+		require.NoError(t, err)
+		assert.Equal(t, &Repository{Root: root, GitDir: gitDir, CommonDir: gitDir}, repo)
+	}
+}
+
+func TestFindRepositoryWorktree(t *testing.T) {
+	dir := t.TempDir()
+	commonDir := filepath.Join(dir, "main", ".git")
+	gitDir := filepath.Join(commonDir, "worktrees", "feature")
+	writeTestFile(t, filepath.Join(commonDir, "HEAD"), "ref: refs/heads/main\n")
+	writeTestFile(t, filepath.Join(gitDir, "HEAD"), "ref: refs/heads/feature\n")
+	writeTestFile(t, filepath.Join(gitDir, "commondir"), "../..\n")
+
+	// Linked worktrees use a .git file pointing at their git directory
+	root := filepath.Join(dir, "feature")
+	writeTestFile(t, filepath.Join(root, ".git"), "gitdir: ../main/.git/worktrees/feature\n")
+
+	repo, err := FindRepository(root)
+	require.NoError(t, err)
+	assert.Equal(t, &Repository{Root: root, GitDir: gitDir, CommonDir: commonDir}, repo)
+}
+
+func TestFindRepositoryNotRepository(t *testing.T) {
+	// A .git directory without a HEAD file isn't a git directory
+	dir := t.TempDir()
+	require.NoError(t, os.MkdirAll(filepath.Join(dir, ".git"), 0o755))
+
+	_, err := FindRepository(dir)
+	assert.ErrorIs(t, err, ErrNotRepository)
+}
+
+func TestTrackedFiles(t *testing.T) {
+	files := []string{
+		"README.md",
+		"a/b/c.go",
+		"a/b/d.go",
+		"a/bc.go",
+		"a.go",
+		"src/日本語.txt",
+	}
+
+	for _, version := range []string{"2", "3", "4"} {
+		t.Run("index version "+version, func(t *testing.T) {
+			root := initTestRepository(t)
+			for _, file := range files {
+				writeTestFile(t, filepath.Join(root, file), file)
+			}
+			runGit(t, root, "add", ".")
+			// Intent-to-add entries use the extended flags of version 3+ indexes
+			writeTestFile(t, filepath.Join(root, "new.txt"), "new")
+			runGit(t, root, "add", "--intent-to-add", "new.txt")
+			runGit(t, root, "update-index", "--index-version", version)
+
+			repo, err := FindRepository(root)
+			require.NoError(t, err)
+			actual, err := repo.TrackedFiles()
+			require.NoError(t, err)
+
+			expected := strings.Split(strings.TrimRight(runGit(t, root, "ls-files", "-z"), "\x00"), "\x00")
+			assert.Equal(t, expected, actual)
+		})
+	}
+}
+
+func TestTrackedFilesEmptyRepository(t *testing.T) {
+	root := initTestRepository(t)
+	repo, err := FindRepository(root)
+	require.NoError(t, err)
+
+	files, err := repo.TrackedFiles()
+	require.NoError(t, err)
+	assert.Empty(t, files)
+}
+
+func TestDecodeIndexVarint(t *testing.T) {
+	tests := []struct {
+		input    []byte
+		expected int
+		n        int
+	}{
+		{[]byte{0x00}, 0, 1},
+		{[]byte{0x7f}, 127, 1},
+		{[]byte{0x80, 0x00}, 128, 2},
+		{[]byte{0x80, 0x7f}, 255, 2},
+		{[]byte{0x81, 0x00}, 256, 2},
+		{[]byte{0x80}, 0, 0},
+		{nil, 0, 0},
+	}
+
+	for _, test := range tests {
+		val, n := decodeIndexVarint(test.input)
+		assert.Equal(t, test.expected, val, "decoding %v", test.input)
+		assert.Equal(t, test.n, n, "decoding %v", test.input)
+	}
+}
+
+// initTestRepository creates an empty git repository using the git binary,
+// skipping the test if git isn't available.
+func initTestRepository(t *testing.T) string {
+	t.Helper()
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git binary not available")
+	}
+
+	root := t.TempDir()
+	runGit(t, root, "init", "--quiet")
+	return root
+}
+
+// runGit runs a git command in the directory provided, returning its output.
+func runGit(t *testing.T, dir string, args ...string) string {
+	t.Helper()
+	cmd := exec.Command("git", args...)
+	cmd.Dir = dir
+	cmd.Env = append(os.Environ(),
+		"GIT_CONFIG_NOSYSTEM=1", "GIT_CONFIG_GLOBAL="+os.DevNull,
+		"GIT_AUTHOR_NAME=Test", "GIT_AUTHOR_EMAIL=test@example.com",
+		"GIT_COMMITTER_NAME=Test", "GIT_COMMITTER_EMAIL=test@example.com",
+	)
+	output, err := cmd.CombinedOutput()
+	require.NoError(t, err, "git %s: %s", strings.Join(args, " "), output)
+	return string(output)
+}
+
+func writeTestFile(t *testing.T, path, contents string) {
+	t.Helper()
+	require.NoError(t, os.MkdirAll(filepath.Dir(path), 0o755))
+	require.NoError(t, os.WriteFile(path, []byte(contents), 0o644))
+}
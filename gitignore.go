@@ -0,0 +1,206 @@
+package codeowners
+
+import (
+	"bufio"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// Gitignore matches paths against the patterns found in .gitignore files and
+// the other places git reads exclude patterns from. Patterns use the same
+// engine as CODEOWNERS rules, extended with negation and directory-only
+// patterns.
+type Gitignore struct {
+	patterns []gitignorePattern
+}
+
+// gitignorePattern is a single pattern from a gitignore file.
+type gitignorePattern struct {
+	// base is the directory containing the file the pattern came from, relative
+	// to the root. Patterns only apply to paths inside it.
+	base    string
+	regex   *regexp.Regexp
+	negated bool
+	dirOnly bool
+}
+
+// LoadGitignore returns a Gitignore holding the exclude patterns that apply to
+// every path under root: those in the user's global excludes file and, if root
+// is the top level of a git repository, its .git/info/exclude file. Patterns
+// from .gitignore files aren't included, and should be added with AddFile as
+// directories are visited.
+func LoadGitignore(root string) (*Gitignore, error) {
+	g := &Gitignore{}
+
+	globalExcludes, err := globalExcludesFile()
+	if err != nil {
+		return nil, err
+	}
+	if globalExcludes != "" {
+		if err := g.AddFile(globalExcludes, ""); err != nil {
+			return nil, err
+		}
+	}
+
+	repo, err := FindRepository(root)
+	if err == nil && samePath(repo.Root, root) {
+		if err := g.AddFile(filepath.Join(repo.CommonDir, "info", "exclude"), ""); err != nil {
+			return nil, err
+		}
+	}
+
+	return g, nil
+}
+
+// AddFile adds the patterns in the gitignore file at path, which apply to paths
+// inside dir. The dir should be relative to the root and use forward slashes,
+// with an empty string used for the root itself. Missing files are ignored.
+// Files added later take precedence, so parent directories' files must be
+// added before their children's.
+func (g *Gitignore) AddFile(path, dir string) error {
+	f, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return nil
+	} else if err != nil {
+		return err
+	}
+	defer f.Close()
+	return g.Add(f, dir)
+}
+
+// Add adds the patterns read from r, which apply to paths inside dir. See
+// AddFile for details.
+func (g *Gitignore) Add(r io.Reader, dir string) error {
+	dir = strings.Trim(filepath.ToSlash(dir), "/")
+	if dir == "." {
+		dir = ""
+	}
+
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		if pat, ok := parseGitignorePattern(scanner.Text()); ok {
+			pat.base = dir
+			g.patterns = append(g.patterns, pat)
+		}
+	}
+	return scanner.Err()
+}
+
+// Match tests whether the path provided is ignored. The path must be relative
+// to the root and isDir must report whether it's a directory. Only the path
+// itself is tested: as with git, the contents of an ignored directory are
+// ignored too, so callers walking a tree should skip ignored directories.
+func (g *Gitignore) Match(testPath string, isDir bool) bool {
+	testPath = filepath.ToSlash(testPath)
+
+	// The last matching pattern decides, so search backwards
+	for i := len(g.patterns) - 1; i >= 0; i-- {
+		pat := g.patterns[i]
+		if pat.dirOnly && !isDir {
+			continue
+		}
+
+		relPath := testPath
+		if pat.base != "" {
+			if !strings.HasPrefix(testPath, pat.base+"/") {
+				continue
+			}
+			relPath = testPath[len(pat.base)+1:]
+		}
+
+		if pat.regex.MatchString(relPath) {
+			return !pat.negated
+		}
+	}
+	return false
+}
+
+// parseGitignorePattern parses a single line of a gitignore file. The boolean
+// return value is false for blank lines, comments, and invalid patterns, which
+// git silently skips.
+func parseGitignorePattern(line string) (gitignorePattern, bool) {
+	line = strings.TrimSuffix(line, "\r")
+
+	// Trailing spaces are ignored unless they're escaped with a backslash
+	end := len(line)
+	for end > 0 && line[end-1] == ' ' && !(end > 1 && line[end-2] == '\\') {
+		end--
+	}
+	line = line[:end]
+
+	if line == "" || line[0] == '#' {
+		return gitignorePattern{}, false
+	}
+
+	pat := gitignorePattern{}
+	if line[0] == '!' {
+		pat.negated = true
+		line = line[1:]
+	}
+
+	// A trailing slash means the pattern only matches directories, but it has
+	// no effect on which paths the rest of the pattern matches
+	if strings.HasSuffix(line, "/") {
+		pat.dirOnly = true
+		line = line[:len(line)-1]
+	}
+	if line == "" || line == "/" {
+		return gitignorePattern{}, false
+	}
+
+	regex, err := buildPatternRegex(line, patternOptions{selfOnly: true, charClasses: true})
+	if err != nil {
+		return gitignorePattern{}, false
+	}
+	pat.regex = regex
+	return pat, true
+}
+
+// globalExcludesFile returns the path to the user's global excludes file, as
+// set by core.excludesFile in their global git config, falling back to git's
+// default location.
+func globalExcludesFile() (string, error) {
+	home, _ := os.UserHomeDir()
+	xdgConfigHome := os.Getenv("XDG_CONFIG_HOME")
+	if xdgConfigHome == "" && home != "" {
+		xdgConfigHome = filepath.Join(home, ".config")
+	}
+
+	// Values in ~/.gitconfig take precedence over the XDG config file
+	var configFiles []string
+	if xdgConfigHome != "" {
+		configFiles = append(configFiles, filepath.Join(xdgConfigHome, "git", "config"))
+	}
+	if home != "" {
+		configFiles = append(configFiles, filepath.Join(home, ".gitconfig"))
+	}
+
+	excludesFile := ""
+	for _, configFile := range configFiles {
+		value, err := readConfigValue(configFile, "core", "excludesfile")
+		if err != nil {
+			return "", err
+		}
+		if value != "" {
+			excludesFile = value
+		}
+	}
+
+	switch {
+	case excludesFile == "" && xdgConfigHome != "":
+		return filepath.Join(xdgConfigHome, "git", "ignore"), nil
+	case strings.HasPrefix(excludesFile, "~/") && home != "":
+		return filepath.Join(home, excludesFile[2:]), nil
+	}
+	return excludesFile, nil
+}
+
+// samePath checks whether two paths refer to the same location once cleaned.
+func samePath(a, b string) bool {
+	absA, errA := filepath.Abs(a)
+	absB, errB := filepath.Abs(b)
+	return errA == nil && errB == nil && absA == absB
+}
@@ -0,0 +1,165 @@
+package codeowners
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGitignoreMatch(t *testing.T) {
+	tests := []struct {
+		name     string
+		contents string
+		dir      string
+		paths    map[string]bool
+		dirs     map[string]bool
+	}{
+		{
+			name:     "single-segment pattern",
+			contents: "*.log\n",
+			paths: map[string]bool{
+				"debug.log":     true,
+				"a/b/debug.log": true,
+				"debug.txt":     false,
+			},
+		},
+		{
+			name:     "character class",
+			contents: "*.py[co]\n",
+			paths: map[string]bool{
+				"a.pyc": true,
+				"a.pyo": true,
+				"a.py":  false,
+			},
+		},
+		{
+			name:     "anchored pattern",
+			contents: "/build\n",
+			paths: map[string]bool{
+				"build":   true,
+				"a/build": false,
+			},
+		},
+		{
+			name:     "pattern with a slash is relative to the file",
+			contents: "a/b\n",
+			dir:      "sub",
+			paths: map[string]bool{
+				"sub/a/b":   true,
+				"a/b":       false,
+				"sub/x/a/b": false,
+			},
+		},
+		{
+			name:     "pattern only matches the path itself",
+			contents: "vendor\n",
+			paths: map[string]bool{
+				"vendor":         true,
+				"vendor/foo.go":  false,
+				"src/vendor":     true,
+				"src/vendor.txt": false,
+			},
+		},
+		{
+			name:     "directory-only pattern",
+			contents: "node_modules/\n",
+			paths: map[string]bool{
+				"node_modules": false,
+			},
+			dirs: map[string]bool{
+				"node_modules":     true,
+				"a/b/node_modules": true,
+			},
+		},
+		{
+			name:     "negated pattern",
+			contents: "*.log\n!keep.log\n",
+			paths: map[string]bool{
+				"debug.log":  true,
+				"keep.log":   false,
+				"a/keep.log": false,
+			},
+		},
+		{
+			name:     "negation only applies to later patterns",
+			contents: "!keep.log\n*.log\n",
+			paths: map[string]bool{
+				"keep.log": true,
+			},
+		},
+		{
+			name:     "comments, blank lines, and trailing spaces",
+			contents: "# *.go\n\nfoo  \nbar\\ \n",
+			paths: map[string]bool{
+				"main.go": false,
+				"foo":     true,
+				"bar":     false,
+				"bar ":    true,
+			},
+		},
+		{
+			name:     "escaped leading characters",
+			contents: "\\#hash\n\\!bang\n",
+			paths: map[string]bool{
+				"#hash": true,
+				"!bang": true,
+			},
+		},
+		{
+			name:     "double asterisks",
+			contents: "docs/**/*.pdf\n",
+			paths: map[string]bool{
+				"docs/a.pdf":     true,
+				"docs/a/b/c.pdf": true,
+				"a/docs/c.pdf":   false,
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			g := &Gitignore{}
+			require.NoError(t, g.Add(strings.NewReader(test.contents), test.dir))
+
+			for path, expected := range test.paths {
+				assert.Equal(t, expected, g.Match(path, false), "file %s", path)
+			}
+			for path, expected := range test.dirs {
+				assert.Equal(t, expected, g.Match(path, true), "directory %s", path)
+			}
+		})
+	}
+}
+
+// Patterns in deeper .gitignore files override those in their parents
+func TestGitignoreNestedFiles(t *testing.T) {
+	g := &Gitignore{}
+	require.NoError(t, g.Add(strings.NewReader("*.gen.go\n"), ""))
+	require.NoError(t, g.Add(strings.NewReader("!*.gen.go\n"), "api"))
+
+	assert.True(t, g.Match("main.gen.go", false))
+	assert.True(t, g.Match("other/main.gen.go", false))
+	assert.False(t, g.Match("api/types.gen.go", false))
+	assert.False(t, g.Match("api/v1/types.gen.go", false))
+}
+
+func TestLoadGitignore(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv("XDG_CONFIG_HOME", "")
+	writeTestFile(t, filepath.Join(home, ".gitconfig"), "[core]\n\texcludesFile = ~/global-ignore\n")
+	writeTestFile(t, filepath.Join(home, "global-ignore"), ".DS_Store\n")
+
+	root := t.TempDir()
+	writeTestFile(t, filepath.Join(root, ".git", "HEAD"), "ref: refs/heads/main\n")
+	writeTestFile(t, filepath.Join(root, ".git", "info", "exclude"), "*.local\n")
+
+	g, err := LoadGitignore(root)
+	require.NoError(t, err)
+	assert.True(t, g.Match("a/.DS_Store", false))
+	assert.True(t, g.Match("settings.local", false))
+	assert.False(t, g.Match("settings.json", false))
+}
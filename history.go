@@ -0,0 +1,116 @@
+package codeowners
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Commit is a commit from a repository's history.
+type Commit struct {
+	Hash        string
+	Author      string
+	AuthorEmail string
+	AuthorTime  time.Time
+	// Files lists the paths changed by the commit, relative to the root of the
+	// repository.
+	Files []string
+}
+
+// LogOptions limit which commits Log returns.
+type LogOptions struct {
+	// Rev is the revision to start from, which defaults to HEAD.
+	Rev string
+	// Since excludes commits authored before this time, if it isn't zero.
+	Since time.Time
+	// MaxCount limits the number of commits returned, if it's positive.
+	MaxCount int
+	// Paths limits the history to commits that changed these paths.
+	Paths []string
+}
+
+// Log returns the non-merge commits reachable from a revision, newest first,
+// along with the files each one changed. Renames are reported as a deletion
+// and an addition, so both paths are included. Reading history requires the
+// git binary.
+func (r *Repository) Log(opts LogOptions) ([]Commit, error) {
+	rev := opts.Rev
+	if rev == "" {
+		rev = "HEAD"
+	}
+	if err := checkRevision(rev); err != nil {
+		return nil, err
+	}
+
+	// Fields are separated by ASCII unit separators, and each commit starts
+	// with a record separator so commits and file lists can be told apart
+	args := []string{
+		"log", "-z", "--no-merges", "--no-renames", "--name-only",
+		"--format=%x1e%H%x1f%an%x1f%ae%x1f%at",
+	}
+	if !opts.Since.IsZero() {
+		// Git filters on commit time, which is almost always later than author
+		// time, so this just narrows down the commits we need to check ourselves
+		args = append(args, fmt.Sprintf("--since=%d", opts.Since.Unix()))
+	}
+	if opts.MaxCount > 0 {
+		args = append(args, fmt.Sprintf("--max-count=%d", opts.MaxCount))
+	}
+	args = append(args, rev, "--")
+	args = append(args, opts.Paths...)
+
+	output, err := r.git(args...)
+	if err != nil {
+		return nil, err
+	}
+	commits, err := parseLog(string(output))
+	if err != nil {
+		return nil, err
+	}
+
+	if !opts.Since.IsZero() {
+		filtered := commits[:0]
+		for _, commit := range commits {
+			if !commit.AuthorTime.Before(opts.Since) {
+				filtered = append(filtered, commit)
+			}
+		}
+		commits = filtered
+	}
+	return commits, nil
+}
+
+// parseLog parses the output of the git log command run by Log.
+func parseLog(output string) ([]Commit, error) {
+	var commits []Commit
+	for _, record := range strings.Split(output, "\x1e") {
+		if record == "" {
+			continue
+		}
+
+		header, files, _ := strings.Cut(record, "\x00")
+		fields := strings.Split(header, "\x1f")
+		if len(fields) != 4 {
+			return nil, fmt.Errorf("invalid log record %q", header)
+		}
+		seconds, err := strconv.ParseInt(fields[3], 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid author time %q", fields[3])
+		}
+
+		commit := Commit{
+			Hash:        fields[0],
+			Author:      fields[1],
+			AuthorEmail: fields[2],
+			AuthorTime:  time.Unix(seconds, 0).UTC(),
+		}
+		for _, file := range strings.Split(strings.TrimLeft(files, "\n"), "\x00") {
+			if file != "" {
+				commit.Files = append(commit.Files, file)
+			}
+		}
+		commits = append(commits, commit)
+	}
+	return commits, nil
+}
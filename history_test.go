@@ -0,0 +1,66 @@
+package codeowners
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLog(t *testing.T) {
+	root := initTestRepository(t)
+	writeTestFile(t, filepath.Join(root, "a.go"), "package a\n")
+	writeTestFile(t, filepath.Join(root, "docs", "b c.md"), "# B\n")
+	runGit(t, root, "add", ".")
+	runGit(t, root, "commit", "--quiet", "-m", "First", "--date", "2020-01-01T00:00:00Z")
+
+	writeTestFile(t, filepath.Join(root, "a.go"), "package a\n\nfunc A() {}\n")
+	runGit(t, root, "commit", "--quiet", "-am", "Second", "--date", "2021-01-01T00:00:00Z", "--author", "Jane <jane@example.com>")
+
+	repo, err := FindRepository(root)
+	require.NoError(t, err)
+
+	commits, err := repo.Log(LogOptions{})
+	require.NoError(t, err)
+	require.Len(t, commits, 2)
+
+	assert.Equal(t, "Jane", commits[0].Author)
+	assert.Equal(t, "jane@example.com", commits[0].AuthorEmail)
+	assert.Equal(t, time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC), commits[0].AuthorTime)
+	assert.Equal(t, []string{"a.go"}, commits[0].Files)
+	assert.Len(t, commits[0].Hash, 40)
+
+	assert.Equal(t, "Test", commits[1].Author)
+	assert.Equal(t, []string{"a.go", "docs/b c.md"}, commits[1].Files)
+
+	commits, err = repo.Log(LogOptions{Paths: []string{"docs"}})
+	require.NoError(t, err)
+	require.Len(t, commits, 1)
+	assert.Equal(t, "Test", commits[0].Author)
+
+	commits, err = repo.Log(LogOptions{Since: time.Date(2020, 6, 1, 0, 0, 0, 0, time.UTC)})
+	require.NoError(t, err)
+	require.Len(t, commits, 1)
+	assert.Equal(t, "Jane", commits[0].Author)
+
+	commits, err = repo.Log(LogOptions{MaxCount: 1})
+	require.NoError(t, err)
+	assert.Len(t, commits, 1)
+}
+
+func TestParseLog(t *testing.T) {
+	output := "\x1eabc\x1fJane\x1fjane@example.com\x1f1600000000\x00\na.go\x00b/c.go\x00" +
+		"\x1edef\x1fJoe\x1fjoe@example.com\x1f1500000000\x00\n"
+
+	commits, err := parseLog(output)
+	require.NoError(t, err)
+	assert.Equal(t, []Commit{
+		{Hash: "abc", Author: "Jane", AuthorEmail: "jane@example.com", AuthorTime: time.Unix(1600000000, 0).UTC(), Files: []string{"a.go", "b/c.go"}},
+		{Hash: "def", Author: "Joe", AuthorEmail: "joe@example.com", AuthorTime: time.Unix(1500000000, 0).UTC()},
+	}, commits)
+
+	_, err = parseLog("\x1eabc\x1fJane\x00")
+	assert.Error(t, err)
+}
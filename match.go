@@ -4,36 +4,123 @@ import (
 	"fmt"
 	"path/filepath"
 	"regexp"
+	"regexp/syntax"
+	"sort"
 	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"golang.org/x/text/unicode/norm"
 )
 
-type pattern struct {
+// Pattern is a compiled gitignore-style path pattern, with the semantics of a
+// CODEOWNERS rule's pattern. It can be used to match paths the same way
+// CODEOWNERS files do in other tools.
+type Pattern struct {
 	pattern             string
 	regex               *regexp.Regexp
 	leftAnchoredLiteral bool
+	opts                patternOptions
+}
+
+// CompilePattern compiles a gitignore-style path pattern, as it would be
+// interpreted in a GitHub CODEOWNERS file.
+func CompilePattern(pattern string) (Pattern, error) {
+	if pattern == "" {
+		return Pattern{}, fmt.Errorf("empty pattern")
+	}
+	return newPattern(pattern, patternOptions{})
 }
 
 // newPattern creates a new pattern struct from a gitignore-style pattern string
-func newPattern(patternStr string) (pattern, error) {
-	pat := pattern{pattern: patternStr}
+func newPattern(patternStr string, opts patternOptions) (Pattern, error) {
+	pat := Pattern{pattern: patternStr, opts: opts}
+
+	source := patternStr
+	if opts.normalizeUnicode {
+		source = norm.NFC.String(patternStr)
+	}
+	patternRegex, err := buildPatternRegex(source, opts)
+	if err != nil {
+		return Pattern{}, err
+	}
+	pat.regex = patternRegex
 
-	if !strings.ContainsAny(patternStr, "*?\\") && patternStr[0] == '/' {
+	// Literal patterns anchored to the root can be matched without the regexp
+	if !strings.ContainsAny(patternStr, opts.wildcards()+"\\") && patternStr[0] == '/' && patternStr != "/" && !opts.caseInsensitive && !opts.normalizeUnicode {
 		pat.leftAnchoredLiteral = true
-	} else {
-		patternRegex, err := buildPatternRegex(patternStr)
-		if err != nil {
-			return pattern{}, err
-		}
-		pat.regex = patternRegex
 	}
 
 	return pat, nil
 }
 
-// match tests if the path provided matches the pattern
-func (p pattern) match(testPath string) (bool, error) {
+// String returns the pattern's source text.
+func (p Pattern) String() string {
+	return p.pattern
+}
+
+// Regexp returns the regular expression the pattern was compiled to, which
+// matches the same slash-separated paths as the pattern.
+func (p Pattern) Regexp() *regexp.Regexp {
+	return p.regex
+}
+
+// IsAnchored reports whether the pattern only matches paths relative to the
+// root, like "/docs/" or "docs/*.md", rather than at any depth, like "*.md" or
+// "**/docs/".
+func (p Pattern) IsAnchored() bool {
+	pattern := p.pattern
+	anchored := strings.HasPrefix(pattern, "/")
+	if anchored {
+		pattern = pattern[1:]
+	}
+	if pattern == "**" || strings.HasPrefix(pattern, "**/") {
+		return false
+	}
+	return anchored || (!p.opts.anyDepth && strings.Contains(strings.TrimSuffix(pattern, "/"), "/"))
+}
+
+// IsDirectoryOnly reports whether the pattern only matches the contents of
+// directories, because it ends with a slash (or "/**"), rather than also
+// matching files with the same name.
+func (p Pattern) IsDirectoryOnly() bool {
+	return strings.HasSuffix(p.pattern, "/") || strings.HasSuffix(p.pattern, "/**")
+}
+
+// LiteralPrefix returns the literal path every path the pattern matches starts
+// with, such as "docs/" for "/docs/*.md", with any escaping removed. The
+// boolean is true if the pattern has no wildcards, in which case it matches
+// the prefix and everything inside it. Patterns that aren't anchored have an
+// empty prefix, as they may match anywhere. For case-insensitive patterns, the
+// prefix is given as written, and for patterns that normalize Unicode, it's in
+// NFC.
+func (p Pattern) LiteralPrefix() (prefix string, complete bool) {
+	if !p.IsAnchored() {
+		return "", false
+	}
+
+	var b strings.Builder
+	escaped := false
+	for _, ch := range strings.TrimPrefix(p.pattern, "/") {
+		switch {
+		case escaped:
+			escaped = false
+		case ch == '\\':
+			escaped = true
+			continue
+		case strings.ContainsRune(p.opts.wildcards(), ch):
+			return p.normalize(b.String()), false
+		}
+		b.WriteRune(ch)
+	}
+	return p.normalize(b.String()), true
+}
+
+// Match tests if the path provided matches the pattern. Windows-style path
+// separators are treated as slashes.
+func (p Pattern) Match(testPath string) bool {
 	// Normalize Windows-style path separators to forward slashes
-	testPath = filepath.ToSlash(testPath)
+	testPath = p.normalize(filepath.ToSlash(testPath))
 
 	if p.leftAnchoredLiteral {
 		prefix := p.pattern
@@ -45,37 +132,121 @@ func (p pattern) match(testPath string) (bool, error) {
 
 		// If the pattern ends with a slash we can do a simple prefix match
 		if prefix[len(prefix)-1] == '/' {
-			return strings.HasPrefix(testPath, prefix), nil
+			return strings.HasPrefix(testPath, prefix)
 		}
 
 		// If the strings are the same length, check for an exact match
 		if len(testPath) == len(prefix) {
-			return testPath == prefix, nil
+			return testPath == prefix
 		}
 
 		// Otherwise check if the test path is a subdirectory of the pattern
 		if len(testPath) > len(prefix) && testPath[len(prefix)] == '/' {
-			return testPath[:len(prefix)] == prefix, nil
+			return testPath[:len(prefix)] == prefix
 		}
 
 		// Otherwise the test path must be shorter than the pattern, so it can't match
-		return false, nil
+		return false
 	}
 
-	return p.regex.MatchString(testPath), nil
+	return p.regex.MatchString(testPath)
+}
+
+// normalize converts a path to NFC if the pattern normalizes Unicode.
+func (p Pattern) normalize(path string) string {
+	if p.opts.normalizeUnicode {
+		return norm.NFC.String(path)
+	}
+	return path
+}
+
+// patternOptions tweak how gitignore-style patterns are compiled. The zero value
+// gives CODEOWNERS semantics.
+type patternOptions struct {
+	// selfOnly stops patterns from matching the descendants of the paths they
+	// match. CODEOWNERS rules apply to everything inside a matching directory,
+	// but .gitignore patterns only match the path itself: callers are expected
+	// to skip the contents of ignored directories.
+	selfOnly bool
+	// anyDepth makes patterns without a leading slash match at any depth, even
+	// if they contain other slashes, as if they started with "**/". Normally a
+	// slash anywhere but the end anchors a pattern to the root.
+	anyDepth bool
+	// caseInsensitive makes patterns match paths regardless of case, as on
+	// case-insensitive file systems.
+	caseInsensitive bool
+	// charClasses makes square brackets match one of a set of characters, as
+	// in "[a-z]", "[!abc]", or "[[:digit:]]", rather than literally.
+	charClasses bool
+	// braces makes patterns like "*.{js,ts}" match any of the comma-separated
+	// alternatives inside the braces, rather than the braces literally.
+	braces bool
+	// normalizeUnicode makes patterns match paths regardless of their Unicode
+	// normalization form, by converting both to NFC.
+	normalizeUnicode bool
+}
+
+// wildcards returns the characters that start a wildcard in patterns compiled
+// with the options.
+func (opts patternOptions) wildcards() string {
+	wildcards := "*?"
+	if opts.charClasses {
+		wildcards += "["
+	}
+	if opts.braces {
+		wildcards += "{"
+	}
+	return wildcards
 }
 
 // buildPatternRegex compiles a new regexp object from a gitignore-style pattern string
-func buildPatternRegex(pattern string) (*regexp.Regexp, error) {
+func buildPatternRegex(pattern string, opts patternOptions) (*regexp.Regexp, error) {
+	if pattern == "" {
+		return nil, fmt.Errorf("empty pattern")
+	}
+
+	alternatives := []string{pattern}
+	if opts.braces {
+		var err error
+		if alternatives, err = expandBraces(pattern, opts.charClasses); err != nil {
+			return nil, err
+		}
+	}
+
+	var re strings.Builder
+	if opts.caseInsensitive {
+		re.WriteString(`(?i)`)
+	}
+	re.WriteString(`\A`)
+	if len(alternatives) > 1 {
+		re.WriteString(`(?:`)
+	}
+	for i, alternative := range alternatives {
+		if i > 0 {
+			re.WriteString(`|`)
+		}
+		if err := writePatternRegex(&re, alternative, opts); err != nil {
+			return nil, err
+		}
+	}
+	if len(alternatives) > 1 {
+		re.WriteString(`)`)
+	}
+	re.WriteString(`\z`)
+	return regexp.Compile(re.String())
+}
+
+// writePatternRegex writes the regular expression for a pattern without brace
+// expansions to re. Patterns that can't match anything, like "/", write
+// nothing, so they only match an empty path.
+func writePatternRegex(re *strings.Builder, pattern string, opts patternOptions) error {
 	// Handle specific edge cases first
 	switch {
 	case strings.Contains(pattern, "***"):
-		return nil, fmt.Errorf("pattern cannot contain three consecutive asterisks")
-	case pattern == "":
-		return nil, fmt.Errorf("empty pattern")
-	case pattern == "/":
+		return fmt.Errorf("pattern cannot contain three consecutive asterisks")
+	case pattern == "" || pattern == "/":
 		// "/" doesn't match anything
-		return regexp.Compile(`\A\z`)
+		return nil
 	}
 
 	segs := strings.Split(pattern, "/")
@@ -86,7 +257,7 @@ func buildPatternRegex(pattern string) (*regexp.Regexp, error) {
 	} else {
 		// No leading slash - check for a single segment pattern, which matches
 		// relative to any descendent path (equivalent to a leading **/)
-		if len(segs) == 1 || (len(segs) == 2 && segs[1] == "") {
+		if len(segs) == 1 || (len(segs) == 2 && segs[1] == "") || opts.anyDepth {
 			if segs[0] != "**" {
 				segs = append([]string{"**"}, segs...)
 			}
@@ -98,12 +269,19 @@ func buildPatternRegex(pattern string) (*regexp.Regexp, error) {
 		segs[len(segs)-1] = "**"
 	}
 
+	// Consecutive "**" segments match the same paths as a single one
+	var collapsed []string
+	for i, seg := range segs {
+		if seg != "**" || i == 0 || segs[i-1] != "**" {
+			collapsed = append(collapsed, seg)
+		}
+	}
+	segs = collapsed
+
 	sep := "/"
 
 	lastSegIndex := len(segs) - 1
 	needSlash := false
-	var re strings.Builder
-	re.WriteString(`\A`)
 	for i, seg := range segs {
 		switch seg {
 		case "**":
@@ -139,15 +317,18 @@ func buildPatternRegex(pattern string) (*regexp.Regexp, error) {
 			}
 
 			escape := false
-			for _, ch := range seg {
+			skipTo := 0
+			for j, ch := range seg {
+				if j < skipTo {
+					// Skip the rest of a character class
+					continue
+				}
 				if escape {
 					escape = false
 					re.WriteString(regexp.QuoteMeta(string(ch)))
 					continue
 				}
 
-				// Other pathspec implementations handle character classes here (e.g.
-				// [AaBb]), but CODEOWNERS doesn't support that so we don't need to
 				switch ch {
 				case '\\':
 					escape = true
@@ -158,6 +339,17 @@ func buildPatternRegex(pattern string) (*regexp.Regexp, error) {
 					// Single-character wildcard
 					re.WriteString(`[^` + sep + `]`)
 				case '[', ']':
+					if ch == '[' && opts.charClasses {
+						class, n, err := charClassRegex(seg[j:])
+						if err != nil {
+							return err
+						}
+						if n > 0 {
+							re.WriteString(class)
+							skipTo = j + n
+							continue
+						}
+					}
 					// Escape square brackets to treat them as literal characters
 					re.WriteString(`\` + string(ch))
 				default:
@@ -166,7 +358,7 @@ func buildPatternRegex(pattern string) (*regexp.Regexp, error) {
 				}
 			}
 
-			if i == lastSegIndex {
+			if i == lastSegIndex && !opts.selfOnly {
 				// As there's no trailing slash (that'd hit the '**' case), we
 				// need to match descendent paths
 				re.WriteString(`(?:` + sep + `.*)?`)
@@ -175,6 +367,491 @@ func buildPatternRegex(pattern string) (*regexp.Regexp, error) {
 			needSlash = true
 		}
 	}
-	re.WriteString(`\z`)
-	return regexp.Compile(re.String())
+	return nil
+}
+
+// maxBraceExpansions limits how many patterns a pattern's braces can expand
+// to, as nested or repeated braces multiply.
+const maxBraceExpansions = 1024
+
+// expandBraces expands the braces in a pattern like "*.{js,ts}" into the
+// patterns they stand for, "*.js" and "*.ts". Braces without a comma, and
+// braces inside character classes if classes is set, are left as they are.
+func expandBraces(pattern string, classes bool) ([]string, error) {
+	open, close, commas := findBraces(pattern, classes)
+	if open < 0 {
+		return []string{pattern}, nil
+	}
+
+	var expanded []string
+	bounds := append(append([]int{open}, commas...), close)
+	for i := 1; i < len(bounds); i++ {
+		alternative := pattern[:open] + pattern[bounds[i-1]+1:bounds[i]] + pattern[close+1:]
+		patterns, err := expandBraces(alternative, classes)
+		if err != nil {
+			return nil, err
+		}
+		expanded = append(expanded, patterns...)
+		if len(expanded) > maxBraceExpansions {
+			return nil, fmt.Errorf("braces expand to more than %d patterns", maxBraceExpansions)
+		}
+	}
+	return expanded, nil
+}
+
+// findBraces finds the first pair of braces in a pattern that contains a comma,
+// returning the positions of the braces and the commas between them that
+// aren't nested in other braces. The positions are -1 if there aren't any.
+func findBraces(pattern string, classes bool) (open, close int, commas []int) {
+	for start := 0; start < len(pattern); start++ {
+		switch {
+		case pattern[start] == '\\':
+			start++
+			continue
+		case pattern[start] == '[' && classes:
+			if _, n, _ := charClassRegex(pattern[start:]); n > 0 {
+				start += n - 1
+			}
+			continue
+		case pattern[start] != '{':
+			continue
+		}
+
+		depth := 0
+		commas = nil
+		for i := start + 1; i < len(pattern); i++ {
+			switch pattern[i] {
+			case '\\':
+				i++
+			case '{':
+				depth++
+			case ',':
+				if depth == 0 {
+					commas = append(commas, i)
+				}
+			case '}':
+				if depth > 0 {
+					depth--
+					continue
+				}
+				if len(commas) > 0 {
+					return start, i, commas
+				}
+				// Braces without a comma are literal, but may contain braces
+				// that aren't
+				i = len(pattern)
+			}
+		}
+	}
+	return -1, -1, nil
+}
+
+// posixClasses are the named character classes that can be used inside
+// square brackets, like "[[:digit:]]".
+var posixClasses = map[string]bool{
+	"alnum": true, "alpha": true, "blank": true, "cntrl": true, "digit": true, "graph": true,
+	"lower": true, "print": true, "punct": true, "space": true, "upper": true, "xdigit": true,
+}
+
+// charClassRegex translates the character class at the start of a pattern,
+// such as "[a-z]" or "[!abc]", into a regular expression, returning it and the
+// length of the class in the pattern. The length is 0 if the opening bracket
+// isn't closed, in which case it should be matched literally. Classes never
+// match a slash, as patterns only match it explicitly.
+func charClassRegex(pattern string) (string, int, error) {
+	i := 1
+	var re strings.Builder
+	re.WriteString(`[`)
+	negated := i < len(pattern) && (pattern[i] == '!' || pattern[i] == '^')
+	if negated {
+		re.WriteString(`^/`)
+		i++
+	}
+
+	first := true
+	for i < len(pattern) {
+		if pattern[i] == ']' && !first {
+			if re.Len() == 1 {
+				// Nothing but "/" was in the class, so it can't match anything
+				return `[^\x00-\x{10FFFF}]`, i + 1, nil
+			}
+			re.WriteString(`]`)
+			return re.String(), i + 1, nil
+		}
+		first = false
+
+		if strings.HasPrefix(pattern[i:], "[:") {
+			end := strings.Index(pattern[i+2:], ":]")
+			if end < 0 {
+				return "", 0, nil
+			}
+			name := pattern[i+2 : i+2+end]
+			if !posixClasses[name] {
+				return "", 0, fmt.Errorf("unknown character class %q", name)
+			}
+			if ranges, ok := posixClassesWithSlash[name]; ok {
+				for _, r := range ranges {
+					writeClassRange(&re, r[0], r[1])
+				}
+			} else {
+				re.WriteString(`[:` + name + `:]`)
+			}
+			i += end + 4
+			continue
+		}
+
+		lo, n := classChar(pattern[i:])
+		if n == 0 {
+			return "", 0, nil
+		}
+		i += n
+		hi := lo
+
+		// A hyphen between two characters makes a range, but is literal at the
+		// end of the class
+		if i+1 < len(pattern) && pattern[i] == '-' && pattern[i+1] != ']' {
+			if hi, n = classChar(pattern[i+1:]); n == 0 {
+				return "", 0, nil
+			}
+			i += 1 + n
+		}
+		writeClassRange(&re, lo, hi)
+	}
+	return "", 0, nil
+}
+
+// posixClassesWithSlash lists the ASCII ranges of the named character classes
+// that include "/", so they can be written without it.
+var posixClassesWithSlash = map[string][][2]rune{
+	"graph": {{'!', '~'}},
+	"print": {{' ', '~'}},
+	"punct": {{'!', '/'}, {':', '@'}, {'[', '`'}, {'{', '~'}},
+}
+
+// writeClassRange writes a range of characters to a regular expression
+// character class, leaving out "/" as git never lets a character class match
+// the separator between path segments.
+func writeClassRange(re *strings.Builder, lo, hi rune) {
+	if lo <= '/' && '/' <= hi {
+		if lo < '/' {
+			writeClassRange(re, lo, '/'-1)
+		}
+		if hi > '/' {
+			writeClassRange(re, '/'+1, hi)
+		}
+		return
+	}
+	re.WriteString(quoteClassChar(lo))
+	if hi != lo {
+		re.WriteString(`-` + quoteClassChar(hi))
+	}
+}
+
+// classChar reads a possibly escaped character at the start of s, returning it
+// and its length in s. The length is 0 if s ends with an escape.
+func classChar(s string) (rune, int) {
+	n := 0
+	if s[0] == '\\' {
+		n = 1
+	}
+	if n >= len(s) {
+		return 0, 0
+	}
+	ch, size := utf8.DecodeRuneInString(s[n:])
+	return ch, n + size
+}
+
+// quoteClassChar quotes a character for use in a regular expression character
+// class.
+func quoteClassChar(ch rune) string {
+	if ch < utf8.RuneSelf && !isAlphanumeric(ch) {
+		return `\` + string(ch)
+	}
+	return string(ch)
+}
+
+// PatternRelation describes how the sets of paths matched by two patterns
+// relate to each other.
+type PatternRelation int
+
+const (
+	// Disjoint patterns have no paths in common.
+	Disjoint PatternRelation = iota
+	// Overlapping patterns have some paths in common, but each matches paths
+	// the other doesn't.
+	Overlapping
+	// Subset means every path the first pattern matches is matched by the
+	// second, which matches other paths too.
+	Subset
+	// Superset means every path the second pattern matches is matched by the
+	// first, which matches other paths too.
+	Superset
+	// Equivalent patterns match exactly the same paths.
+	Equivalent
+)
+
+// String returns the relation's name, such as "subset".
+func (r PatternRelation) String() string {
+	switch r {
+	case Disjoint:
+		return "disjoint"
+	case Overlapping:
+		return "overlapping"
+	case Subset:
+		return "subset"
+	case Superset:
+		return "superset"
+	case Equivalent:
+		return "equivalent"
+	default:
+		return fmt.Sprintf("PatternRelation(%d)", int(r))
+	}
+}
+
+// Compare works out how the set of paths the pattern matches relates to the
+// set other matches, without needing a list of paths: Subset means every path
+// p matches is matched by other. It walks the automata the patterns'
+// regular expressions compile to in step, so the answer holds for every
+// possible path. A pattern that can't match any path, like "/", is a subset of
+// every other pattern.
+func (p Pattern) Compare(other Pattern) (PatternRelation, error) {
+	return newPatternComparer().compare(p, other)
+}
+
+// patternComparer compares patterns, caching the automata their regular
+// expressions compile to so each is only built once.
+type patternComparer struct {
+	progs map[*regexp.Regexp]prog
+}
+
+func newPatternComparer() *patternComparer {
+	return &patternComparer{progs: map[*regexp.Regexp]prog{}}
+}
+
+func (c *patternComparer) prog(re *regexp.Regexp) (prog, error) {
+	if p, ok := c.progs[re]; ok {
+		return p, nil
+	}
+	p, err := compileProg(re)
+	if err != nil {
+		return prog{}, err
+	}
+	c.progs[re] = p
+	return p, nil
+}
+
+func (c *patternComparer) compare(p, other Pattern) (PatternRelation, error) {
+	// Anchored patterns whose literal prefixes diverge can't match the same
+	// path, which saves building the automata for most pairs of rules
+	if !p.opts.caseInsensitive && !other.opts.caseInsensitive && p.opts.normalizeUnicode == other.opts.normalizeUnicode {
+		prefixA, _ := p.LiteralPrefix()
+		prefixB, _ := other.LiteralPrefix()
+		if !strings.HasPrefix(prefixA, prefixB) && !strings.HasPrefix(prefixB, prefixA) {
+			return Disjoint, nil
+		}
+	}
+
+	a, err := c.prog(p.regex)
+	if err != nil {
+		return 0, err
+	}
+	b, err := c.prog(other.regex)
+	if err != nil {
+		return 0, err
+	}
+
+	classes := runeClasses(a, b)
+	type pair struct{ a, b progState }
+	start := pair{a.start(), b.start()}
+	seen := map[string]bool{start.a.key() + "|" + start.b.key(): true}
+	queue := []pair{start}
+	var onlyA, onlyB, both bool
+	for len(queue) > 0 && !(onlyA && onlyB && both) {
+		cur := queue[0]
+		queue = queue[1:]
+
+		for _, r := range classes {
+			next := pair{a.step(cur.a, r), b.step(cur.b, r)}
+			if len(next.a.pcs) == 0 && len(next.b.pcs) == 0 {
+				continue
+			}
+			// Paths are never empty, so acceptance is only checked after a step
+			matchA, matchB := a.accepts(next.a), b.accepts(next.b)
+			onlyA = onlyA || (matchA && !matchB)
+			onlyB = onlyB || (matchB && !matchA)
+			both = both || (matchA && matchB)
+
+			key := next.a.key() + "|" + next.b.key()
+			if !seen[key] {
+				seen[key] = true
+				queue = append(queue, next)
+			}
+		}
+	}
+
+	switch {
+	case !onlyA && !onlyB:
+		return Equivalent, nil
+	case !onlyA:
+		return Subset, nil
+	case !onlyB:
+		return Superset, nil
+	case !both:
+		return Disjoint, nil
+	default:
+		return Overlapping, nil
+	}
+}
+
+// Subsumes reports whether every path other matches is also matched by p.
+func (p Pattern) Subsumes(other Pattern) (bool, error) {
+	rel, err := other.Compare(p)
+	return rel == Subset || rel == Equivalent, err
+}
+
+// prog is a regular expression compiled to a non-deterministic automaton,
+// which can be simulated one rune at a time.
+type prog struct {
+	*syntax.Prog
+}
+
+// progState is the set of instructions a prog may be at after consuming some
+// input. It holds instructions that consume a rune, match instructions, and
+// empty-width assertions that couldn't be passed yet.
+type progState struct {
+	pcs []uint32
+}
+
+func (s progState) key() string {
+	var b strings.Builder
+	for _, pc := range s.pcs {
+		fmt.Fprintf(&b, "%d,", pc)
+	}
+	return b.String()
+}
+
+func compileProg(re *regexp.Regexp) (prog, error) {
+	parsed, err := syntax.Parse(re.String(), syntax.Perl)
+	if err != nil {
+		return prog{}, err
+	}
+	p, err := syntax.Compile(parsed.Simplify())
+	if err != nil {
+		return prog{}, err
+	}
+	return prog{p}, nil
+}
+
+func (p prog) start() progState {
+	return p.closure([]uint32{uint32(p.Start)}, true, false)
+}
+
+// step consumes a rune, returning the new state.
+func (p prog) step(s progState, r rune) progState {
+	var next []uint32
+	for _, pc := range s.pcs {
+		inst := &p.Inst[pc]
+		switch inst.Op {
+		case syntax.InstRune, syntax.InstRune1, syntax.InstRuneAny, syntax.InstRuneAnyNotNL:
+			if inst.MatchRune(r) {
+				next = append(next, inst.Out)
+			}
+		}
+	}
+	return p.closure(next, false, false)
+}
+
+// accepts checks whether the input consumed to reach the state is a match.
+func (p prog) accepts(s progState) bool {
+	for _, pc := range p.closure(s.pcs, false, true).pcs {
+		if p.Inst[pc].Op == syntax.InstMatch {
+			return true
+		}
+	}
+	return false
+}
+
+// closure follows the instructions that don't consume input from pcs. Only
+// the start and end of text assertions are supported, as those are the only
+// ones patterns compile to.
+func (p prog) closure(pcs []uint32, atStart, atEnd bool) progState {
+	var flags syntax.EmptyOp
+	if atStart {
+		flags |= syntax.EmptyBeginText | syntax.EmptyBeginLine
+	}
+	if atEnd {
+		flags |= syntax.EmptyEndText | syntax.EmptyEndLine
+	}
+
+	seen := map[uint32]bool{}
+	var out []uint32
+	var visit func(pc uint32)
+	visit = func(pc uint32) {
+		if seen[pc] {
+			return
+		}
+		seen[pc] = true
+		inst := &p.Inst[pc]
+		switch inst.Op {
+		case syntax.InstAlt, syntax.InstAltMatch:
+			visit(inst.Out)
+			visit(inst.Arg)
+		case syntax.InstCapture, syntax.InstNop:
+			visit(inst.Out)
+		case syntax.InstEmptyWidth:
+			if syntax.EmptyOp(inst.Arg)&^flags == 0 {
+				visit(inst.Out)
+			} else {
+				out = append(out, pc)
+			}
+		case syntax.InstFail:
+		default:
+			out = append(out, pc)
+		}
+	}
+	for _, pc := range pcs {
+		visit(pc)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i] < out[j] })
+	return progState{pcs: out}
+}
+
+// runeClasses splits the runes into ranges that every instruction in the progs
+// treats the same way, returning the first rune of each range. Simulating the
+// progs on these runes covers every possible input.
+func runeClasses(progs ...prog) []rune {
+	bounds := map[rune]bool{0: true, '\n': true, '\n' + 1: true}
+	for _, p := range progs {
+		for _, inst := range p.Inst {
+			switch inst.Op {
+			case syntax.InstRune1:
+				bounds[inst.Rune[0]] = true
+				bounds[inst.Rune[0]+1] = true
+			case syntax.InstRune:
+				fold := syntax.Flags(inst.Arg)&syntax.FoldCase != 0
+				for i := 0; i+1 < len(inst.Rune); i += 2 {
+					bounds[inst.Rune[i]] = true
+					bounds[inst.Rune[i+1]+1] = true
+				}
+				if fold && len(inst.Rune) == 1 {
+					r := inst.Rune[0]
+					bounds[r] = true
+					bounds[r+1] = true
+					for f := unicode.SimpleFold(r); f != r; f = unicode.SimpleFold(f) {
+						bounds[f] = true
+						bounds[f+1] = true
+					}
+				}
+			}
+		}
+	}
+
+	classes := make([]rune, 0, len(bounds))
+	for r := range bounds {
+		if r <= unicode.MaxRune {
+			classes = append(classes, r)
+		}
+	}
+	sort.Slice(classes, func(i, j int) bool { return classes[i] < classes[j] })
+	return classes
 }
@@ -38,15 +38,14 @@ func TestMatch(t *testing.T) {
 
 		t.Run(test.Name, func(t *testing.T) {
 			for path, shouldMatch := range test.Paths {
-				pattern, err := newPattern(test.Pattern)
+				pattern, err := newPattern(test.Pattern, patternOptions{})
 				require.NoError(t, err)
 
 				// Debugging tips:
 				// - Print the generated regex: `fmt.Println(pattern.regex.String())`
 				// - Only run a single case by adding `"focus" : true` to the test in the JSON file
 
-				actual, err := pattern.match(path)
-				require.NoError(t, err)
+				actual := pattern.Match(path)
 
 				if shouldMatch {
 					assert.True(t, actual, "expected pattern %s to match path %s", test.Pattern, path)
@@ -57,3 +56,192 @@ func TestMatch(t *testing.T) {
 		})
 	}
 }
+
+func TestMatchIgnoreCase(t *testing.T) {
+	tests := []struct {
+		pattern string
+		paths   map[string]bool
+	}{
+		{"/Docs/README.md", map[string]bool{"docs/readme.md": true, "DOCS/README.MD": true, "docs/readme.txt": false}},
+		{"/src/", map[string]bool{"SRC/main.go": true, "lib/src/main.go": false}},
+		{"*.GO", map[string]bool{"cmd/main.go": true, "main.gox": false}},
+	}
+	for _, test := range tests {
+		pattern, err := newPattern(test.pattern, patternOptions{caseInsensitive: true})
+		require.NoError(t, err)
+		for path, shouldMatch := range test.paths {
+			actual := pattern.Match(path)
+			assert.Equal(t, shouldMatch, actual, "pattern %s, path %s", test.pattern, path)
+		}
+	}
+
+	// Matching is case-sensitive by default
+	pattern, err := newPattern("/Docs/README.md", patternOptions{})
+	require.NoError(t, err)
+	actual := pattern.Match("docs/readme.md")
+	assert.False(t, actual)
+}
+
+func TestMatchExtendedPatterns(t *testing.T) {
+	tests := []struct {
+		pattern string
+		paths   map[string]bool
+	}{
+		{"/src/[a-c]*.go", map[string]bool{"src/api.go": true, "src/cmd.go": true, "src/main.go": false}},
+		{"*.[!o]", map[string]bool{"main.c": true, "main.o": false, "main.cc": false}},
+		{"*.[^o]", map[string]bool{"main.c": true, "main.o": false}},
+		{"/v[[:digit:]]/", map[string]bool{"v1/api.go": true, "vx/api.go": false}},
+		{"/a[!x]b", map[string]bool{"acb": true, "a/b": false}},
+		{"/[]a]", map[string]bool{"]": true, "a": true, "b": false}},
+		{"/[a-]", map[string]bool{"a": true, "-": true, "b": false}},
+		{"/\\[ab]", map[string]bool{"[ab]": true, "a": false}},
+		{"/[ab", map[string]bool{"[ab": true, "a": false}},
+		{"*.{js,ts}", map[string]bool{"app.js": true, "lib/app.ts": true, "app.go": false, "app.{js,ts}": false}},
+		{"/{app,lib}/**/*.rb", map[string]bool{"app/models/user.rb": true, "lib/tasks.rb": true, "spec/user_spec.rb": false}},
+		{"/{a,b{c,d}}", map[string]bool{"a": true, "bc": true, "bd": true, "b": false}},
+		{"/{a}", map[string]bool{"{a}": true, "a": false}},
+		{"/a\\{b,c}", map[string]bool{"a{b,c}": true, "ab": false}},
+		{"/[{]a,b}", map[string]bool{"{a,b}": true, "a": false}},
+		{"/a[.-0]b", map[string]bool{"a.b": true, "a0b": true, "a/b": false}},
+		{"/a[/]b", map[string]bool{"a/b": false, "ab": false}},
+		{"/a[[:punct:]]b", map[string]bool{"a-b": true, "a~b": true, "a/b": false}},
+	}
+	for _, test := range tests {
+		pattern, err := newPattern(test.pattern, patternOptions{charClasses: true, braces: true})
+		require.NoError(t, err, test.pattern)
+		for path, shouldMatch := range test.paths {
+			actual := pattern.Match(path)
+			assert.Equal(t, shouldMatch, actual, "pattern %s, path %s", test.pattern, path)
+		}
+	}
+
+	_, err := newPattern("/[[:nope:]]", patternOptions{charClasses: true})
+	assert.EqualError(t, err, `unknown character class "nope"`)
+
+	// Square brackets and braces are literal by default
+	pattern, err := newPattern("/[ab]/{c,d}", patternOptions{})
+	require.NoError(t, err)
+	assert.True(t, pattern.Match("[ab]/{c,d}"))
+	assert.False(t, pattern.Match("a/c"))
+}
+
+func TestMatchUnicodeNormalization(t *testing.T) {
+	nfc, nfd := "/docs/caf\u00e9/", "docs/cafe\u0301/menu.md"
+
+	// The forms don't match each other by default
+	pattern, err := newPattern(nfc, patternOptions{})
+	require.NoError(t, err)
+	assert.True(t, pattern.Match("docs/caf\u00e9/menu.md"))
+	assert.False(t, pattern.Match(nfd))
+
+	pattern, err = newPattern(nfc, patternOptions{normalizeUnicode: true})
+	require.NoError(t, err)
+	assert.True(t, pattern.Match("docs/caf\u00e9/menu.md"))
+	assert.True(t, pattern.Match(nfd))
+	assert.False(t, pattern.Match("docs/cafe/menu.md"))
+	assert.Equal(t, nfc, pattern.String())
+
+	pattern, err = newPattern("*/cafe\u0301/*.md", patternOptions{normalizeUnicode: true})
+	require.NoError(t, err)
+	assert.True(t, pattern.Match("docs/caf\u00e9/menu.md"))
+	assert.True(t, pattern.Match(nfd))
+}
+
+func TestCompilePattern(t *testing.T) {
+	tests := []struct {
+		pattern       string
+		anchored      bool
+		directoryOnly bool
+		prefix        string
+		complete      bool
+	}{
+		{"*.md", false, false, "", false},
+		{"docs/", false, true, "", false},
+		{"**/docs/", false, true, "", false},
+		{"/**/docs", false, false, "", false},
+		{"/docs/", true, true, "docs/", true},
+		{"/docs", true, false, "docs", true},
+		{"docs/*.md", true, false, "docs/", false},
+		{"/src/**", true, true, "src/", false},
+		{"/a\\ b/c?d", true, false, "a b/c", false},
+		{"/", true, true, "", true},
+	}
+	for _, test := range tests {
+		p, err := CompilePattern(test.pattern)
+		require.NoError(t, err)
+		assert.Equal(t, test.pattern, p.String())
+		assert.Equal(t, test.anchored, p.IsAnchored(), "IsAnchored(%q)", test.pattern)
+		assert.Equal(t, test.directoryOnly, p.IsDirectoryOnly(), "IsDirectoryOnly(%q)", test.pattern)
+		prefix, complete := p.LiteralPrefix()
+		assert.Equal(t, test.prefix, prefix, "LiteralPrefix(%q)", test.pattern)
+		assert.Equal(t, test.complete, complete, "LiteralPrefix(%q)", test.pattern)
+	}
+
+	p, err := CompilePattern("/docs/")
+	require.NoError(t, err)
+	assert.True(t, p.Match("docs/index.md"))
+	assert.True(t, p.Regexp().MatchString("docs/index.md"))
+	assert.False(t, p.Match("src/docs/index.md"))
+	assert.False(t, p.Regexp().MatchString("src/docs/index.md"))
+
+	p, err = CompilePattern("/")
+	require.NoError(t, err)
+	assert.False(t, p.Match("docs"))
+
+	_, err = CompilePattern("")
+	assert.EqualError(t, err, "empty pattern")
+	_, err = CompilePattern("a/***")
+	assert.Error(t, err)
+}
+
+func TestPatternCompare(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want PatternRelation
+	}{
+		{"/docs/", "/docs/", Equivalent},
+		{"/docs/", "/docs/**", Equivalent},
+		{"docs/*.md", "/docs/*.md", Equivalent},
+		{"/docs/*.md", "*.md", Subset},
+		{"/docs/api/", "/docs/", Subset},
+		{"/docs/", "/docs/api/", Superset},
+		{"**/*.md", "*.md", Equivalent},
+		{"/*.go", "/*.md", Disjoint},
+		{"*.go", "*.md", Overlapping}, // e.g. a.go/b.md
+		{"/src/", "/docs/", Disjoint},
+		{"/src/*", "*.go", Overlapping},
+		{"/docs/", "docs/", Subset},
+		{"/a?c", "/abc", Superset},
+		{"/", "*", Subset},
+		{"/", "/", Equivalent},
+		{"/docs", "/docs/", Superset},
+	}
+	for _, test := range tests {
+		a, err := CompilePattern(test.a)
+		require.NoError(t, err)
+		b, err := CompilePattern(test.b)
+		require.NoError(t, err)
+
+		rel, err := a.Compare(b)
+		require.NoError(t, err)
+		assert.Equal(t, test.want, rel, "%s vs %s: got %s", test.a, test.b, rel)
+	}
+}
+
+func TestPatternCompareIgnoreCase(t *testing.T) {
+	a, err := newPattern("/Docs/", patternOptions{caseInsensitive: true})
+	require.NoError(t, err)
+	b, err := CompilePattern("/docs/")
+	require.NoError(t, err)
+
+	rel, err := a.Compare(b)
+	require.NoError(t, err)
+	assert.Equal(t, Superset, rel)
+
+	subsumes, err := a.Subsumes(b)
+	require.NoError(t, err)
+	assert.True(t, subsumes)
+	subsumes, err = b.Subsumes(a)
+	require.NoError(t, err)
+	assert.False(t, subsumes)
+}
@@ -0,0 +1,165 @@
+package codeowners
+
+import (
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// ScopedRuleset is a ruleset from a CODEOWNERS file that only applies to one
+// directory, such as packages/foo/CODEOWNERS in a monorepo.
+type ScopedRuleset struct {
+	// Path is the path to the CODEOWNERS file relative to the root of the
+	// repository.
+	Path string
+	// Dir is the directory the file's patterns are relative to. An empty
+	// string refers to the root of the repository.
+	Dir     string
+	Ruleset Ruleset
+}
+
+// NestedFilePaths picks out the CODEOWNERS files from a list of paths relative
+// to the root of a repository (e.g. from Repository.TrackedFiles). The paths
+// are returned sorted by directory, parents first.
+func NestedFilePaths(files []string) []string {
+	var paths []string
+	for _, file := range files {
+		if path.Base(file) == "CODEOWNERS" {
+			paths = append(paths, file)
+		}
+	}
+	sort.Slice(paths, func(i, j int) bool {
+		if parentDir(paths[i]) != parentDir(paths[j]) {
+			return parentDir(paths[i]) < parentDir(paths[j])
+		}
+		return paths[i] < paths[j]
+	})
+	return paths
+}
+
+// LoadNestedFiles loads a set of CODEOWNERS files from the repository at root,
+// such as those returned by NestedFilePaths. Paths are relative to the root.
+// Files at the standard locations (see LoadFileFromStandardLocation) apply to
+// the whole repository, while files anywhere else apply to the directory they
+// are in. Like GitHub, only the first standard location that has a file is
+// used, and the rest are skipped. Each file's dialect is detected from its
+// path, unless WithDialect is given.
+func LoadNestedFiles(root string, paths []string, options ...ParseOption) ([]ScopedRuleset, error) {
+	rootFile := ""
+	for _, location := range standardLocations {
+		for _, p := range paths {
+			if p == location && rootFile == "" {
+				rootFile = p
+			}
+		}
+	}
+
+	var rulesets []ScopedRuleset
+	for _, p := range paths {
+		dir := parentDir(p)
+		if isStandardLocation(p) {
+			if p != rootFile {
+				continue
+			}
+			dir = ""
+		}
+
+		f, err := os.Open(filepath.Join(root, filepath.FromSlash(p)))
+		if err != nil {
+			return nil, err
+		}
+		ruleset, err := ParseFile(f, append([]ParseOption{WithDialect(DetectDialect(p))}, options...)...)
+		f.Close()
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", p, err)
+		}
+		rulesets = append(rulesets, ScopedRuleset{Path: p, Dir: dir, Ruleset: ruleset})
+	}
+	return rulesets, nil
+}
+
+// isStandardLocation checks whether a path relative to the root of a
+// repository is one of the standard locations for CODEOWNERS files.
+func isStandardLocation(p string) bool {
+	for _, location := range standardLocations {
+		if p == location {
+			return true
+		}
+	}
+	return false
+}
+
+// MergeRulesets combines rulesets from several CODEOWNERS files into a single
+// ruleset for the whole repository. Each ruleset's patterns are rebased onto
+// its directory so they can't match anything outside it, then the rulesets are
+// concatenated with parent directories first. As the last matching rule wins,
+// a file's owners come from the CODEOWNERS file closest to it that has a
+// matching rule, and rulesets for the same directory are applied in the order
+// they're given.
+func MergeRulesets(rulesets []ScopedRuleset) (Ruleset, error) {
+	sorted := append([]ScopedRuleset{}, rulesets...)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return sorted[i].Dir < sorted[j].Dir
+	})
+
+	merged := Ruleset{}
+	for _, scoped := range sorted {
+		rebased, err := scoped.Ruleset.Rebase(scoped.Dir)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", scoped.Path, err)
+		}
+		merged = append(merged, rebased...)
+	}
+	return merged, nil
+}
+
+// Rebase rewrites the ruleset's patterns, which are taken to be relative to the
+// directory provided, so they're relative to the root of the repository
+// instead. For example, "/docs/" becomes "/dir/docs/", and "*.go" becomes
+// "/dir/**/*.go". Everything but the patterns is kept.
+func (r Ruleset) Rebase(dir string) (Ruleset, error) {
+	dir = cleanDir(dir)
+	if dir == "" {
+		return r, nil
+	}
+
+	rebased := make(Ruleset, 0, len(r))
+	for _, rule := range r {
+		newRule := rule
+		pat, err := newPattern(rebasePattern(rule.RawPattern(), dir, rule.pattern.opts.anyDepth), rule.pattern.opts)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", rule.LineNumber, err)
+		}
+		newRule.pattern = pat
+		rebased = append(rebased, newRule)
+	}
+	return rebased, nil
+}
+
+// rebasePattern makes a pattern that's relative to dir relative to the root.
+// If anyDepth is set, as in GitLab files, relative patterns match at any depth
+// even if they contain a slash.
+func rebasePattern(pattern, dir string, anyDepth bool) string {
+	prefix := "/" + escapePattern(dir)
+	switch {
+	case pattern == "/":
+		// Matches nothing, wherever it is
+		return pattern
+	case pattern == "*" || pattern == "**":
+		return prefix + "/"
+	case strings.HasPrefix(pattern, "/"):
+		return prefix + pattern
+	case strings.HasPrefix(pattern, "**/"):
+		return prefix + "/" + pattern
+	case !anyDepth && strings.Contains(strings.TrimSuffix(pattern, "/"), "/"):
+		// Patterns with a slash before the end are relative to the file's
+		// directory, just like ones with a leading slash
+		return prefix + "/" + pattern
+	default:
+		// Other patterns match at any depth
+		return prefix + "/**/" + pattern
+	}
+}
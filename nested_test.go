@@ -0,0 +1,114 @@
+package codeowners
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRebasePattern(t *testing.T) {
+	tests := map[string]string{
+		"*":          "/pkg/foo/",
+		"**":         "/pkg/foo/",
+		"/docs/":     "/pkg/foo/docs/",
+		"/README.md": "/pkg/foo/README.md",
+		"src/*.go":   "/pkg/foo/src/*.go",
+		"*.go":       "/pkg/foo/**/*.go",
+		"test/":      "/pkg/foo/**/test/",
+		"**/fixture": "/pkg/foo/**/fixture",
+		"/":          "/",
+	}
+	for pattern, expected := range tests {
+		assert.Equal(t, expected, rebasePattern(pattern, "pkg/foo", false), pattern)
+	}
+	assert.Equal(t, `/my\ pkg/**/*.go`, rebasePattern("*.go", "my pkg", false))
+
+	// GitLab matches relative patterns at any depth, even with a slash
+	assert.Equal(t, "/pkg/foo/**/src/*.go", rebasePattern("src/*.go", "pkg/foo", true))
+	assert.Equal(t, "/pkg/foo/**/fixture", rebasePattern("**/fixture", "pkg/foo", true))
+	assert.Equal(t, "/pkg/foo/docs/", rebasePattern("/docs/", "pkg/foo", true))
+}
+
+// Rebasing a ruleset keeps the paths it matches within the directory
+func TestRebaseMatches(t *testing.T) {
+	for _, dialect := range []Dialect{GitHub, GitLab} {
+		ruleset, err := ParseFile(strings.NewReader("* @a\ndocs/api @b\n/src/ @c\n*.md @d\n"), WithDialect(dialect))
+		require.NoError(t, err)
+		rebased, err := ruleset.Rebase("pkg/foo")
+		require.NoError(t, err)
+
+		for _, path := range []string{"README.md", "docs/api/x.go", "lib/docs/api/x.go", "src/main.go", "lib/src/main.go", "lib/x.go"} {
+			want, err := ruleset.Match(path)
+			require.NoError(t, err)
+			got, err := rebased.Match("pkg/foo/" + path)
+			require.NoError(t, err)
+			require.NotNil(t, got, "%s: %s", dialect, path)
+			assert.Equal(t, want.LineNumber, got.LineNumber, "%s: %s", dialect, path)
+		}
+	}
+}
+
+func TestNestedFilePaths(t *testing.T) {
+	paths := NestedFilePaths([]string{
+		"pkg/foo/CODEOWNERS",
+		"pkg/foo/Bar/CODEOWNERS",
+		"README.md",
+		".github/CODEOWNERS",
+		"pkg/foo/main.go",
+	})
+	assert.Equal(t, []string{".github/CODEOWNERS", "pkg/foo/CODEOWNERS", "pkg/foo/Bar/CODEOWNERS"}, paths)
+}
+
+func TestMergeRulesets(t *testing.T) {
+	root := t.TempDir()
+	writeTestFile(t, filepath.Join(root, "CODEOWNERS"), "* @org/everyone\n*.md @org/docs\n/pkg/foo/legacy/ @org/legacy\n")
+	writeTestFile(t, filepath.Join(root, "docs", "CODEOWNERS"), "* @org/ignored\n")
+	writeTestFile(t, filepath.Join(root, "pkg", "foo", "CODEOWNERS"), "* @org/foo # Foo team\n*.md @org/foo-docs\n")
+	writeTestFile(t, filepath.Join(root, "pkg", "foo", "api", "CODEOWNERS"), "/v1/ @org/api\n")
+
+	paths := NestedFilePaths([]string{"pkg/foo/api/CODEOWNERS", "docs/CODEOWNERS", "pkg/foo/CODEOWNERS", "CODEOWNERS"})
+	rulesets, err := LoadNestedFiles(root, paths)
+	require.NoError(t, err)
+
+	var dirs []string
+	for _, r := range rulesets {
+		dirs = append(dirs, r.Dir)
+	}
+	// docs/CODEOWNERS is a standard location that's shadowed by the root file
+	assert.Equal(t, []string{"", "pkg/foo", "pkg/foo/api"}, dirs)
+
+	merged, err := MergeRulesets([]ScopedRuleset{rulesets[2], rulesets[0], rulesets[1]})
+	require.NoError(t, err)
+
+	var rules []string
+	for _, rule := range merged {
+		rules = append(rules, rule.String())
+	}
+	assert.Equal(t, []string{
+		"* @org/everyone",
+		"*.md @org/docs",
+		"/pkg/foo/legacy/ @org/legacy",
+		"/pkg/foo/ @org/foo # Foo team",
+		"/pkg/foo/**/*.md @org/foo-docs",
+		"/pkg/foo/api/v1/ @org/api",
+	}, rules)
+
+	for path, expected := range map[string]string{
+		"README.md":                    "@org/docs",
+		"pkg/bar/main.go":              "@org/everyone",
+		"pkg/foo/main.go":              "@org/foo",
+		"pkg/foo/legacy/old.go":        "@org/foo",
+		"pkg/foo/docs/guide.md":        "@org/foo-docs",
+		"pkg/foo/api/v1/users.go":      "@org/api",
+		"pkg/foo/api/v2/users.go":      "@org/foo",
+		"pkg/foo/api/v1/v1/CHANGES.md": "@org/api",
+	} {
+		rule, err := merged.Match(path)
+		require.NoError(t, err)
+		require.NotNil(t, rule, path)
+		assert.Equal(t, expected, rule.Owners[0].String(), path)
+	}
+}
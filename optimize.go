@@ -0,0 +1,247 @@
+package codeowners
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Optimize shortens a ruleset without changing who owns anything. It removes
+// rules that are always overridden by a later rule, and rules that are
+// redundant because an earlier rule with the same owners already covers them.
+// Both are proven by comparing patterns (see Pattern.Compare), so they hold
+// for every possible path. If files is given, rules with the same owners in a
+// directory are also merged into a single rule for the directory, such as
+// "/a/*.go" and "/a/*.md" into "/a/", but only if no file in the list changes
+// owners as a result. The returned messages describe each change.
+//
+// Rulesets with GitLab sections or exclusions aren't supported.
+func (r Ruleset) Optimize(files []string) (Ruleset, []string, error) {
+	for _, rule := range r {
+		if rule.Section != nil || rule.Excluded {
+			return nil, nil, fmt.Errorf("line %d: rulesets with sections or exclusions can't be optimized", rule.LineNumber)
+		}
+	}
+
+	o := optimizer{rules: append(Ruleset{}, r...), comparer: newPatternComparer()}
+	for changed := true; changed; {
+		var err error
+		if changed, err = o.removeOverridden(); err != nil {
+			return nil, nil, err
+		}
+		if !changed {
+			if changed, err = o.removeRedundant(); err != nil {
+				return nil, nil, err
+			}
+		}
+		if !changed && files != nil {
+			if changed, err = o.mergeDirectories(files); err != nil {
+				return nil, nil, err
+			}
+		}
+	}
+
+	if files != nil {
+		path, err := changedOwners(r, o.rules, files)
+		if err != nil {
+			return nil, nil, err
+		}
+		if path != "" {
+			return nil, nil, fmt.Errorf("optimizing changed the owners of %s", path)
+		}
+	}
+	return o.rules, o.messages, nil
+}
+
+type optimizer struct {
+	rules    Ruleset
+	comparer *patternComparer
+	messages []string
+}
+
+// removeOverridden removes the first rule whose paths are all matched by a
+// later rule, which always takes precedence.
+func (o *optimizer) removeOverridden() (bool, error) {
+	for i := range o.rules {
+		for j := len(o.rules) - 1; j > i; j-- {
+			rel, err := o.comparer.compare(o.rules[i].pattern, o.rules[j].pattern)
+			if err != nil {
+				return false, err
+			}
+			if rel == Subset || rel == Equivalent {
+				o.messages = append(o.messages, fmt.Sprintf("line %d: removed %q as it's always overridden by %q on line %d",
+					o.rules[i].LineNumber, o.rules[i].RawPattern(), o.rules[j].RawPattern(), o.rules[j].LineNumber))
+				o.remove(i)
+				return true, nil
+			}
+		}
+	}
+	return false, nil
+}
+
+// removeRedundant removes the first rule whose paths would otherwise fall
+// through to an earlier rule with the same owners.
+func (o *optimizer) removeRedundant() (bool, error) {
+	for j := range o.rules {
+		for i := j - 1; i >= 0; i-- {
+			rel, err := o.comparer.compare(o.rules[j].pattern, o.rules[i].pattern)
+			if err != nil {
+				return false, err
+			}
+			if rel == Disjoint {
+				// Rules that can't match the same paths can be skipped over
+				continue
+			}
+			if (rel == Subset || rel == Equivalent) && ownersKey(o.rules[i].Owners) == ownersKey(o.rules[j].Owners) {
+				o.messages = append(o.messages, fmt.Sprintf("line %d: removed %q as %q on line %d already gives its paths the same owners",
+					o.rules[j].LineNumber, o.rules[j].RawPattern(), o.rules[i].RawPattern(), o.rules[i].LineNumber))
+				o.remove(j)
+				return true, nil
+			}
+			break
+		}
+	}
+	return false, nil
+}
+
+// mergeDirectories replaces the first group of rules with the same owners in
+// a directory with a single rule for the directory, if that doesn't change the
+// owners of any of the files.
+func (o *optimizer) mergeDirectories(files []string) (bool, error) {
+	// Try the deepest directories first so groups grow from the bottom up
+	seen := map[string]bool{}
+	var dirs []string
+	for _, rule := range o.rules {
+		if dir := ruleDir(rule); dir != "" && !seen[dir] {
+			seen[dir] = true
+			dirs = append(dirs, dir)
+		}
+	}
+	sort.Slice(dirs, func(i, j int) bool {
+		if depthI, depthJ := strings.Count(dirs[i], "/"), strings.Count(dirs[j], "/"); depthI != depthJ {
+			return depthI > depthJ
+		}
+		return dirs[i] < dirs[j]
+	})
+
+	for _, dir := range dirs {
+		var keys []string
+		groups := map[string][]int{}
+		for i, rule := range o.rules {
+			if len(rule.Owners) > 0 && isUnder(ruleDir(rule), dir) {
+				key := ownersKey(rule.Owners)
+				if groups[key] == nil {
+					keys = append(keys, key)
+				}
+				groups[key] = append(groups[key], i)
+			}
+		}
+
+		for _, key := range keys {
+			members := groups[key]
+			// Directories containing "#" can't be written as patterns
+			if len(members) < 2 || checkPatternPath(dir) != nil {
+				continue
+			}
+			merged, ok, err := o.tryMerge(members, "/"+escapePattern(dir)+"/", dir, files)
+			if err != nil {
+				return false, err
+			}
+			if ok {
+				o.messages = append(o.messages, merged)
+				return true, nil
+			}
+		}
+	}
+	return false, nil
+}
+
+// tryMerge replaces the rules at the indexes provided with a rule for a
+// directory, placed where the last of them was, and keeps the change if no
+// file in the directory changes owners.
+func (o *optimizer) tryMerge(members []int, pattern, dir string, files []string) (string, bool, error) {
+	last := o.rules[members[len(members)-1]]
+	pat, err := newPattern(pattern, last.pattern.opts)
+	if err != nil {
+		return "", false, err
+	}
+	rule := Rule{pattern: pat, Owners: last.Owners, Comment: last.Comment, LineNumber: last.LineNumber}
+
+	isMember := map[int]bool{}
+	for _, i := range members {
+		isMember[i] = true
+	}
+	candidate := make(Ruleset, 0, len(o.rules)-len(members)+1)
+	var lines []string
+	for i, r := range o.rules {
+		if !isMember[i] {
+			candidate = append(candidate, r)
+			continue
+		}
+		lines = append(lines, fmt.Sprintf("%q on line %d", r.RawPattern(), r.LineNumber))
+		if i == members[len(members)-1] {
+			candidate = append(candidate, rule)
+		}
+	}
+
+	// Only files in the directory can be affected, as every rule involved is
+	// inside it. Matching them with a pattern for the directory folds case and
+	// normalises paths the same way the rules do.
+	dirPattern, err := newPattern("/"+escapePattern(dir)+"/", last.pattern.opts)
+	if err != nil {
+		return "", false, err
+	}
+	var affected []string
+	for _, file := range files {
+		if dirPattern.Match(file) {
+			affected = append(affected, file)
+		}
+	}
+	if len(affected) == 0 {
+		return "", false, nil
+	}
+	if path, err := changedOwners(o.rules, candidate, affected); path != "" || err != nil {
+		return "", false, err
+	}
+
+	o.rules = candidate
+	return fmt.Sprintf("merged %s into %q", strings.Join(lines, ", "), pattern), true, nil
+}
+
+func (o *optimizer) remove(i int) {
+	o.rules = append(o.rules[:i:i], o.rules[i+1:]...)
+}
+
+// ruleDir returns the deepest directory that everything a rule matches is
+// inside, or an empty string if its pattern isn't anchored.
+func ruleDir(rule Rule) string {
+	prefix, complete := rule.pattern.LiteralPrefix()
+	if complete && strings.HasSuffix(prefix, "/") {
+		return strings.TrimSuffix(prefix, "/")
+	}
+	return parentDir(prefix)
+}
+
+// isUnder checks whether a directory is dir or inside it.
+func isUnder(path, dir string) bool {
+	return path == dir || strings.HasPrefix(path, dir+"/")
+}
+
+// changedOwners returns the first file whose owners differ between the
+// rulesets, or an empty string if there isn't one.
+func changedOwners(a, b Ruleset, files []string) (string, error) {
+	for _, file := range files {
+		ownersA, err := a.owners(file)
+		if err != nil {
+			return "", err
+		}
+		ownersB, err := b.owners(file)
+		if err != nil {
+			return "", err
+		}
+		if ownersKey(ownersA) != ownersKey(ownersB) {
+			return file, nil
+		}
+	}
+	return "", nil
+}
@@ -0,0 +1,139 @@
+package codeowners
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestOptimizeRemovesOverriddenRules(t *testing.T) {
+	ruleset, err := ParseFile(bytes.NewBufferString("/docs/api.md @alice\n* @everyone\n/docs/ @docs\n/src/*.go @bob\n"))
+	require.NoError(t, err)
+
+	optimized, messages, err := ruleset.Optimize(nil)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"*", "/docs/", "/src/*.go"}, rawPatterns(optimized))
+	assert.Equal(t, []string{
+		`line 1: removed "/docs/api.md" as it's always overridden by "/docs/" on line 3`,
+	}, messages)
+}
+
+func TestOptimizeRemovesRedundantRules(t *testing.T) {
+	file := "* @everyone\n/src/ @bob\n/docs/ @docs\n/src/main.go @bob\n/src/cmd/ @alice\n/src/cmd/main.go @alice\n"
+	ruleset, err := ParseFile(bytes.NewBufferString(file))
+	require.NoError(t, err)
+
+	optimized, messages, err := ruleset.Optimize(nil)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"*", "/src/", "/docs/", "/src/cmd/"}, rawPatterns(optimized))
+	assert.Equal(t, []string{
+		`line 4: removed "/src/main.go" as "/src/" on line 2 already gives its paths the same owners`,
+		`line 6: removed "/src/cmd/main.go" as "/src/cmd/" on line 5 already gives its paths the same owners`,
+	}, messages)
+}
+
+func TestOptimizeKeepsRulesThatChangeOwners(t *testing.T) {
+	// The second rule for main.go undoes the one for /src/cmd/, so neither can go
+	file := "/src/ @bob\n/src/cmd/ @alice\n/src/cmd/main.go @bob\n"
+	ruleset, err := ParseFile(bytes.NewBufferString(file))
+	require.NoError(t, err)
+
+	optimized, messages, err := ruleset.Optimize(nil)
+	require.NoError(t, err)
+	assert.Equal(t, rawPatterns(ruleset), rawPatterns(optimized))
+	assert.Empty(t, messages)
+}
+
+func TestOptimizeMergesDirectories(t *testing.T) {
+	file := "* @everyone\n/a/*.go @bob\n/a/*.md @bob # Docs\n/b/*.go @bob\n/b/*.md @alice\n"
+	ruleset, err := ParseFile(bytes.NewBufferString(file))
+	require.NoError(t, err)
+	files := []string{"README.md", "a/main.go", "a/README.md", "b/main.go", "b/README.md"}
+
+	optimized, messages, err := ruleset.Optimize(files)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"*", "/a/", "/b/*.go", "/b/*.md"}, rawPatterns(optimized))
+	assert.Equal(t, "Docs", optimized[1].Comment)
+	assert.Equal(t, 3, optimized[1].LineNumber)
+	assert.Equal(t, []string{
+		`merged "/a/*.go" on line 2, "/a/*.md" on line 3 into "/a/"`,
+	}, messages)
+
+	for _, file := range files {
+		want, err := ruleset.Match(file)
+		require.NoError(t, err)
+		got, err := optimized.Match(file)
+		require.NoError(t, err)
+		assert.Equal(t, want.Owners, got.Owners, file)
+	}
+}
+
+func TestOptimizeMergesDirectoriesIgnoringCase(t *testing.T) {
+	file := "/a/*.go @bob\n/a/*.md @bob\n"
+	ruleset, err := ParseFile(bytes.NewBufferString(file), WithIgnoreCase(true))
+	require.NoError(t, err)
+
+	optimized, _, err := ruleset.Optimize([]string{"a/main.go", "a/README.md"})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"/a/"}, rawPatterns(optimized))
+	rule, err := optimized.Match("A/Makefile")
+	require.NoError(t, err)
+	require.NotNil(t, rule)
+	assert.Equal(t, "/a/", rule.RawPattern())
+}
+
+func TestOptimizeDoesntMergeWhenOwnersWouldChange(t *testing.T) {
+	file := "* @everyone\n/a/*.go @bob\n/a/*.md @bob\n"
+	ruleset, err := ParseFile(bytes.NewBufferString(file))
+	require.NoError(t, err)
+
+	// a/Makefile would be given to @bob rather than @everyone
+	optimized, messages, err := ruleset.Optimize([]string{"a/main.go", "a/README.md", "a/Makefile"})
+	require.NoError(t, err)
+	assert.Equal(t, rawPatterns(ruleset), rawPatterns(optimized))
+	assert.Empty(t, messages)
+}
+
+func TestOptimizeDoesntMergeWhenOwnersWouldChangeIgnoringCase(t *testing.T) {
+	file := "* @everyone\n/a/*.go @bob\n/a/*.md @bob\n"
+	ruleset, err := ParseFile(bytes.NewBufferString(file), WithIgnoreCase(true))
+	require.NoError(t, err)
+
+	// A/Makefile is in the same directory as far as the rules are concerned,
+	// and would be given to @bob rather than @everyone
+	optimized, messages, err := ruleset.Optimize([]string{"a/main.go", "a/README.md", "A/Makefile"})
+	require.NoError(t, err)
+	assert.Equal(t, rawPatterns(ruleset), rawPatterns(optimized))
+	assert.Empty(t, messages)
+}
+
+func TestOptimizeDoesntMergeDirectoriesWithHash(t *testing.T) {
+	// "/a#b/" can't be written, as "#" starts a comment
+	goRule, err := NewRule("/a#b/*.go", []Owner{{Value: "bob", Type: UsernameOwner}})
+	require.NoError(t, err)
+	mdRule, err := NewRule("/a#b/*.md", goRule.Owners)
+	require.NoError(t, err)
+
+	optimized, messages, err := Ruleset{goRule, mdRule}.Optimize([]string{"a#b/main.go", "a#b/README.md"})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"/a#b/*.go", "/a#b/*.md"}, rawPatterns(optimized))
+	assert.Empty(t, messages)
+}
+
+func TestOptimizeRejectsSections(t *testing.T) {
+	ruleset, err := ParseFile(bytes.NewBufferString("[Docs] @docs\n/docs/\n"), WithDialect(GitLab))
+	require.NoError(t, err)
+
+	_, _, err = ruleset.Optimize(nil)
+	assert.EqualError(t, err, "line 2: rulesets with sections or exclusions can't be optimized")
+}
+
+func rawPatterns(ruleset Ruleset) []string {
+	var patterns []string
+	for _, rule := range ruleset {
+		patterns = append(patterns, rule.RawPattern())
+	}
+	return patterns
+}
@@ -0,0 +1,426 @@
+package codeowners
+
+import (
+	"fmt"
+	"path"
+	"sort"
+	"strings"
+)
+
+// Ruleset converts the tree into an equivalent CODEOWNERS ruleset. Each OWNERS
+// file becomes a rule for its directory, owned by everyone the directory
+// inherits ownership from, and each per-file pattern becomes a rule that
+// follows it. Directories anyone may approve changes to are left unowned.
+//
+// CODEOWNERS can't combine the owners of several rules, so a file matching more
+// than one per-file pattern gets the owners of the last one only. Problems like
+// this that can be detected are returned as warnings, along with per-file
+// patterns that can't be converted at all.
+func (t *OwnersTree) Ruleset() (Ruleset, []string, error) {
+	var dirs []string
+	for _, f := range t.files {
+		if path.Base(f.Path) == OwnersFileName {
+			dirs = append(dirs, f.Dir())
+		}
+	}
+	// A directory sorts after every directory it's inside, as they're prefixes
+	sort.Strings(dirs)
+
+	var (
+		ruleset  Ruleset
+		warnings []string
+	)
+	ruleOwners := map[string]string{}
+	for _, dir := range dirs {
+		f := t.files[path.Join(dir, OwnersFileName)]
+		if err := checkPatternPath(dir); err != nil {
+			warnings = append(warnings, fmt.Sprintf("%s: skipping directory %v", f.Path, err))
+			continue
+		}
+		dirOwners := t.inheritedOwners(dir)
+
+		inherited := ""
+		for parent := dir; parent != ""; {
+			parent = parentDir(parent)
+			if key, ok := ruleOwners[parent]; ok {
+				inherited = key
+				break
+			}
+		}
+		if ownersKey(dirOwners) != inherited {
+			rule, err := NewRule(dirPattern(dir), dirOwners)
+			if err != nil {
+				return nil, nil, fmt.Errorf("%s: %w", f.Path, err)
+			}
+			ruleset = append(ruleset, rule)
+		}
+		ruleOwners[dir] = ownersKey(dirOwners)
+
+		for _, perFile := range mergePerFile(f.PerFile) {
+			m := &OwnersMatch{}
+			t.add(m, perFile.OwnersList, map[string]bool{}, map[string]bool{})
+			if !perFile.NoParent {
+				// Only per-file patterns can cause errors, and they're skipped
+				_ = t.match(m, path.Join(dir, generateProbe), false)
+			}
+			owners := m.Owners
+			if m.Anyone {
+				owners = nil
+			}
+
+			glob := perFile.Patterns[0]
+			if err := checkPatternPath(glob); err != nil {
+				warnings = append(warnings, fmt.Sprintf("%s line %d: per-file pattern %v", f.Path, perFile.LineNumber, err))
+				continue
+			}
+			pattern, ok := globToPattern(glob)
+			if !ok {
+				warnings = append(warnings, fmt.Sprintf("%s line %d: per-file pattern %q uses a character class, which CODEOWNERS doesn't support",
+					f.Path, perFile.LineNumber, glob))
+				continue
+			}
+			if strings.Contains(glob, "/") {
+				warnings = append(warnings, fmt.Sprintf("%s line %d: per-file pattern %q matches files in subdirectories, whose own OWNERS files won't be combined with it",
+					f.Path, perFile.LineNumber, glob))
+			}
+
+			if dir != "" {
+				pattern = escapePattern(dir) + "/" + pattern
+			}
+			rule, err := NewRule("/"+pattern, owners)
+			if err != nil {
+				return nil, nil, fmt.Errorf("%s line %d: %w", f.Path, perFile.LineNumber, err)
+			}
+			ruleset = append(ruleset, rule)
+		}
+	}
+	return ruleset, warnings, nil
+}
+
+// mergePerFile combines per-file lines by pattern, as several lines may give
+// owners for the same pattern (e.g. one saying "set noparent" and one listing
+// owners). Each of the results has a single pattern, and they're in the order
+// the patterns first appear.
+func mergePerFile(perFile []PerFileOwners) []PerFileOwners {
+	var merged []PerFileOwners
+	index := map[string]int{}
+	for _, pf := range perFile {
+		for _, pattern := range pf.Patterns {
+			i, ok := index[pattern]
+			if !ok {
+				i = len(merged)
+				index[pattern] = i
+				merged = append(merged, PerFileOwners{Patterns: []string{pattern}, LineNumber: pf.LineNumber})
+			}
+			list := &merged[i].OwnersList
+			list.Owners = append(list.Owners, pf.Owners...)
+			list.Includes = append(list.Includes, pf.Includes...)
+			list.Anyone = list.Anyone || pf.Anyone
+			list.NoParent = list.NoParent || pf.NoParent
+		}
+	}
+	return merged
+}
+
+// inheritedOwners returns the owners of the files in a directory, ignoring
+// per-file lines. It returns nil if anyone may approve changes.
+func (t *OwnersTree) inheritedOwners(dir string) []Owner {
+	m := &OwnersMatch{}
+	// Only per-file patterns can cause errors, and they're skipped
+	_ = t.match(m, path.Join(dir, generateProbe), false)
+	if m.Anyone {
+		return nil
+	}
+	return m.Owners
+}
+
+// ownerSet returns the set of owners' string representations.
+func ownerSet(owners []Owner) map[string]bool {
+	set := make(map[string]bool, len(owners))
+	for _, o := range owners {
+		set[o.String()] = true
+	}
+	return set
+}
+
+// dirPattern returns a CODEOWNERS pattern matching everything in a directory.
+func dirPattern(dir string) string {
+	if dir == "" {
+		return "*"
+	}
+	return "/" + escapePattern(dir) + "/"
+}
+
+// globToPattern converts a path.Match glob into an equivalent CODEOWNERS
+// pattern, which is only possible if it has no character classes.
+func globToPattern(glob string) (string, bool) {
+	var b strings.Builder
+	escaped := false
+	for _, ch := range glob {
+		switch {
+		case escaped:
+			escaped = false
+			b.WriteString(escapePattern(string(ch)))
+		case ch == '\\':
+			escaped = true
+		case ch == '[':
+			return "", false
+		case ch == '*':
+			// Consecutive asterisks would match across directories in CODEOWNERS
+			if !strings.HasSuffix(b.String(), "*") {
+				b.WriteRune(ch)
+			}
+		case ch == '?':
+			b.WriteRune(ch)
+		default:
+			b.WriteString(escapePattern(string(ch)))
+		}
+	}
+	return b.String(), true
+}
+
+// OwnersTreeFromRuleset converts a CODEOWNERS ruleset into a tree of OWNERS
+// files. Rules for directories (patterns like "/dir/") become OWNERS files that
+// say "set noparent", as CODEOWNERS rules replace owners rather than adding to
+// them, and other anchored rules (like "/dir/*.go") become per-file lines in
+// their directory's OWNERS file. A pattern like "/dir/name" could be a file or
+// a directory, so it becomes both a per-file line and an OWNERS file for the
+// directory, which callers may leave out if it turns out to be a file.
+// Patterns that can match at any depth, or that have wildcards in directory
+// names, can't be expressed by OWNERS files and are skipped with a warning.
+// Rules that are overridden by a later rule for a parent directory are
+// dropped, and also produce warnings.
+func OwnersTreeFromRuleset(ruleset Ruleset) (*OwnersTree, []string, error) {
+	type perFileRule struct {
+		glob string
+		rule *Rule
+	}
+	type dirRules struct {
+		rule    *Rule
+		perFile []perFileRule
+	}
+
+	var warnings []string
+	dirs := map[string]*dirRules{}
+	sortedKeys := func(m map[string]*dirRules) []string {
+		keys := make([]string, 0, len(m))
+		for key := range m {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		return keys
+	}
+	// A rule can be split into several parts, so it's only reported as dropped
+	// the first time
+	dropped := map[*Rule]bool{}
+	drop := func(old *Rule, msg string) {
+		if !dropped[old] {
+			dropped[old] = true
+			warnings = append(warnings, msg)
+		}
+	}
+	addDir := func(dir string, rule *Rule) {
+		// The rule overrides earlier rules for the directory and everything in it
+		for _, other := range sortedKeys(dirs) {
+			if other != dir && !(dir == "" || strings.HasPrefix(other, dir+"/")) {
+				continue
+			}
+			rules := dirs[other]
+			if rules.rule != nil {
+				drop(rules.rule, fmt.Sprintf("line %d: dropping %q as it's overridden by %q on line %d",
+					rules.rule.LineNumber, rules.rule.RawPattern(), rule.RawPattern(), rule.LineNumber))
+			}
+			for _, pf := range rules.perFile {
+				drop(pf.rule, fmt.Sprintf("line %d: dropping %q as it's overridden by %q on line %d",
+					pf.rule.LineNumber, pf.rule.RawPattern(), rule.RawPattern(), rule.LineNumber))
+			}
+			delete(dirs, other)
+		}
+		dirs[dir] = &dirRules{rule: rule}
+	}
+	addPerFile := func(dir, glob string, rule *Rule) {
+		rules, ok := dirs[dir]
+		if !ok {
+			rules = &dirRules{}
+			dirs[dir] = rules
+		}
+		perFile := rules.perFile[:0]
+		for _, pf := range rules.perFile {
+			if pf.glob == glob {
+				drop(pf.rule, fmt.Sprintf("line %d: dropping %q as it's overridden by line %d",
+					pf.rule.LineNumber, pf.rule.RawPattern(), rule.LineNumber))
+				continue
+			}
+			perFile = append(perFile, pf)
+		}
+		rules.perFile = append(perFile, perFileRule{glob: glob, rule: rule})
+	}
+
+	for i := range ruleset {
+		rule := &ruleset[i]
+		if reason := unconvertibleRule(rule); reason != "" {
+			pattern := rule.RawPattern()
+			if rule.Excluded {
+				pattern = "!" + pattern
+			}
+			warnings = append(warnings, fmt.Sprintf("line %d: skipping %q: %s", rule.LineNumber, pattern, reason))
+			continue
+		}
+		dir, glob, name, err := splitOwnersPattern(rule.RawPattern())
+		if err != nil {
+			warnings = append(warnings, fmt.Sprintf("line %d: skipping %q: %v", rule.LineNumber, rule.RawPattern(), err))
+			continue
+		}
+
+		if glob == "" {
+			addDir(dir, rule)
+			continue
+		}
+		addPerFile(dir, glob, rule)
+		if name != "" {
+			// "/docs" matches a file called docs, or everything in a directory
+			addDir(path.Join(dir, name), rule)
+		}
+	}
+
+	files := make([]*OwnersFile, 0, len(dirs))
+	for dir, rules := range dirs {
+		f := &OwnersFile{Path: path.Join(dir, OwnersFileName)}
+		if rules.rule != nil {
+			f.Owners = rules.rule.Owners
+			f.NoParent = dir != ""
+		}
+		for _, pf := range rules.perFile {
+			f.PerFile = append(f.PerFile, PerFileOwners{
+				Patterns:   []string{pf.glob},
+				OwnersList: OwnersList{Owners: pf.rule.Owners, NoParent: true},
+			})
+		}
+		files = append(files, f)
+	}
+
+	tree, err := NewOwnersTree(files)
+	if err != nil {
+		return nil, nil, err
+	}
+	return tree, warnings, nil
+}
+
+// unconvertibleRule explains why a rule's meaning can't be expressed by OWNERS
+// files, beyond what its pattern looks like, or returns an empty string if it
+// can be.
+func unconvertibleRule(rule *Rule) string {
+	pattern, opts := rule.RawPattern(), rule.pattern.opts
+	switch {
+	case rule.Excluded:
+		return "exclusions can't be expressed by OWNERS files"
+	case opts.anyDepth && !strings.HasPrefix(pattern, "/") && strings.Contains(strings.TrimSuffix(pattern, "/"), "/"):
+		return "pattern matches at any depth"
+	case opts.charClasses && hasCharClass(pattern):
+		return "pattern uses a character class"
+	case opts.braces:
+		if open, _, _ := findBraces(pattern, opts.charClasses); open >= 0 {
+			return "pattern uses braces"
+		}
+	}
+	return ""
+}
+
+// splitOwnersPattern splits a CODEOWNERS pattern into the directory it applies
+// to and a path.Match glob for the files in that directory. The glob is empty
+// for patterns that match a whole directory. A final "*" segment, as in
+// "/dir/*", only matches the files directly in the directory, so it's a glob.
+// If the last segment has no wildcards, the pattern also matches everything
+// in a directory of that name, and the unescaped name is returned too.
+func splitOwnersPattern(pattern string) (string, string, string, error) {
+	switch pattern {
+	case "*", "**", "/**":
+		return "", "", "", nil
+	}
+
+	trimmed := strings.TrimSuffix(pattern, "/")
+	if !strings.HasPrefix(pattern, "/") && !strings.Contains(trimmed, "/") {
+		return "", "", "", fmt.Errorf("pattern matches at any depth")
+	}
+
+	pattern = strings.TrimPrefix(pattern, "/")
+	isDir := false
+	for _, suffix := range []string{"/**", "/"} {
+		if strings.HasSuffix(pattern, suffix) {
+			pattern = strings.TrimSuffix(pattern, suffix)
+			isDir = true
+			break
+		}
+	}
+
+	segs := strings.Split(pattern, "/")
+	dirSegs := segs
+	if !isDir {
+		dirSegs = segs[:len(segs)-1]
+	}
+	for _, seg := range segs {
+		if seg == "**" {
+			return "", "", "", fmt.Errorf("pattern uses \"**\"")
+		}
+	}
+
+	var dir strings.Builder
+	for i, seg := range dirSegs {
+		if i > 0 {
+			dir.WriteByte('/')
+		}
+		escaped := false
+		for _, ch := range seg {
+			switch {
+			case escaped:
+				escaped = false
+				dir.WriteRune(ch)
+			case ch == '\\':
+				escaped = true
+			case ch == '*' || ch == '?':
+				return "", "", "", fmt.Errorf("pattern has wildcards in a directory name")
+			default:
+				dir.WriteRune(ch)
+			}
+		}
+	}
+	if isDir {
+		return dir.String(), "", "", nil
+	}
+
+	// Square brackets are literal in CODEOWNERS, but start character classes in
+	// globs, so they need escaping
+	var glob, name strings.Builder
+	escaped, wildcards := false, false
+	for _, ch := range segs[len(segs)-1] {
+		switch {
+		case ch == ',' || ch == '=':
+			return "", "", "", fmt.Errorf("%q can't be used in per-file patterns", ch)
+		case escaped:
+			escaped = false
+			glob.WriteString(escapeGlob(ch))
+			name.WriteRune(ch)
+		case ch == '\\':
+			escaped = true
+		case ch == '*' || ch == '?':
+			wildcards = true
+			glob.WriteRune(ch)
+		default:
+			glob.WriteString(escapeGlob(ch))
+			name.WriteRune(ch)
+		}
+	}
+	if wildcards {
+		return dir.String(), glob.String(), "", nil
+	}
+	return dir.String(), glob.String(), name.String(), nil
+}
+
+// escapeGlob escapes a character if it's special in path.Match globs.
+func escapeGlob(ch rune) string {
+	switch ch {
+	case '*', '?', '[', ']', '\\':
+		return "\\" + string(ch)
+	}
+	return string(ch)
+}
@@ -0,0 +1,438 @@
+package codeowners
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// OwnersFileName is the name of Chromium-style OWNERS files.
+const OwnersFileName = "OWNERS"
+
+// OwnersList is a set of owners in an OWNERS file, either for a whole directory
+// or for the files matched by a per-file line.
+type OwnersList struct {
+	Owners []Owner
+	// Anyone is set by a "*" line, meaning anyone may approve changes.
+	Anyone bool
+	// NoParent is set by a "set noparent" line, which stops owners being
+	// inherited from parent directories.
+	NoParent bool
+	// Includes lists the paths of other OWNERS files whose owners are included,
+	// relative to the root of the repository.
+	Includes []string
+}
+
+// PerFileOwners are owners that only apply to files in the OWNERS file's
+// directory that match one of the patterns, which are path.Match globs
+// relative to the directory. They're given by "per-file" lines.
+type PerFileOwners struct {
+	Patterns []string
+	OwnersList
+	LineNumber int
+}
+
+// OwnersFile is a Chromium-style OWNERS file. Unlike CODEOWNERS, the owners of
+// a file are the owners listed in every OWNERS file in its directory and the
+// directories above it, up to the first one that says "set noparent".
+type OwnersFile struct {
+	// Path is the path to the file relative to the root of the repository.
+	Path string
+	OwnersList
+	PerFile []PerFileOwners
+}
+
+// Dir returns the directory the OWNERS file applies to, relative to the root of
+// the repository. An empty string refers to the root itself.
+func (f *OwnersFile) Dir() string {
+	return parentDir(f.Path)
+}
+
+// ParseOwnersFile parses an OWNERS file. The path is where the file lives
+// relative to the root of the repository, which is needed to resolve includes.
+// Owners are parsed with the default owner matchers, so GitHub usernames and
+// teams may be used alongside email addresses; pass WithOwnerMatchers() to
+// override them.
+func ParseOwnersFile(r io.Reader, filePath string, options ...ParseOption) (*OwnersFile, error) {
+	opts := parseOptions{ownerMatchers: DefaultOwnerMatchers}
+	for _, opt := range options {
+		opt(&opts)
+	}
+
+	f := &OwnersFile{Path: cleanDir(filePath)}
+	scanner := bufio.NewScanner(r)
+	lineNo := 0
+	for scanner.Scan() {
+		lineNo++
+		line := scanner.Text()
+		if i := strings.IndexByte(line, '#'); i >= 0 {
+			line = line[:i]
+		}
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
+
+		if rest, ok := cutPrefix(line, "per-file "); ok {
+			perFile, err := parsePerFileLine(f.Dir(), rest, opts)
+			if err != nil {
+				return nil, fmt.Errorf("line %d: %w", lineNo, err)
+			}
+			perFile.LineNumber = lineNo
+			f.PerFile = append(f.PerFile, perFile)
+			continue
+		}
+
+		if err := parseOwnersDirective(&f.OwnersList, f.Dir(), line, opts); err != nil {
+			return nil, fmt.Errorf("line %d: %w", lineNo, err)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return f, nil
+}
+
+// parsePerFileLine parses the part of a per-file line after "per-file ", which
+// looks like "<pattern>[,<pattern>...]=<directive>[,<directive>...]".
+func parsePerFileLine(dir, line string, opts parseOptions) (PerFileOwners, error) {
+	patterns, directives, ok := strings.Cut(line, "=")
+	if !ok {
+		return PerFileOwners{}, fmt.Errorf("per-file line is missing '='")
+	}
+
+	perFile := PerFileOwners{}
+	for _, pattern := range strings.Split(patterns, ",") {
+		pattern = strings.TrimSpace(pattern)
+		if pattern == "" {
+			return PerFileOwners{}, fmt.Errorf("empty per-file pattern")
+		}
+		if _, err := path.Match(pattern, ""); err != nil {
+			return PerFileOwners{}, fmt.Errorf("invalid per-file pattern %q", pattern)
+		}
+		perFile.Patterns = append(perFile.Patterns, pattern)
+	}
+
+	for _, directive := range strings.Split(directives, ",") {
+		if err := parseOwnersDirective(&perFile.OwnersList, dir, strings.TrimSpace(directive), opts); err != nil {
+			return PerFileOwners{}, err
+		}
+	}
+	return perFile, nil
+}
+
+// parseOwnersDirective parses a single directive from an OWNERS file, which may
+// be an owner, "*", "set noparent", or an include, adding it to the list.
+func parseOwnersDirective(list *OwnersList, dir, directive string, opts parseOptions) error {
+	switch {
+	case directive == "*":
+		list.Anyone = true
+	case directive == "set noparent":
+		list.NoParent = true
+	case strings.HasPrefix(directive, "file:"):
+		// Included files are relative to the directory, unless they start with
+		// "//" for the root of the repository
+		includePath := strings.TrimPrefix(directive, "file:")
+		if rootPath, ok := cutPrefix(includePath, "//"); ok {
+			includePath = rootPath
+		} else {
+			includePath = path.Join(dir, includePath)
+		}
+		list.Includes = append(list.Includes, cleanDir(includePath))
+	case strings.HasPrefix(directive, "include "):
+		// Includes are relative to the directory, unless they start with a slash
+		includePath := strings.TrimSpace(strings.TrimPrefix(directive, "include "))
+		if !strings.HasPrefix(includePath, "/") {
+			includePath = path.Join(dir, includePath)
+		}
+		list.Includes = append(list.Includes, cleanDir(includePath))
+	case directive == "":
+		return fmt.Errorf("empty owner")
+	default:
+		owner, err := newOwner(directive, opts.ownerMatchers)
+		if err != nil {
+			return err
+		}
+		list.Owners = append(list.Owners, owner)
+	}
+	return nil
+}
+
+// cutPrefix removes a prefix from a string, reporting whether it was found.
+func cutPrefix(s, prefix string) (string, bool) {
+	if !strings.HasPrefix(s, prefix) {
+		return s, false
+	}
+	return s[len(prefix):], true
+}
+
+// WriteOwnersFile writes an OWNERS file to w: "set noparent" and "*" first,
+// then includes and owners, then any per-file lines.
+func WriteOwnersFile(w io.Writer, f *OwnersFile) error {
+	bw := bufio.NewWriter(w)
+	lines := ownersListDirectives(f.OwnersList)
+	for _, line := range lines {
+		fmt.Fprintln(bw, line)
+	}
+
+	if len(f.PerFile) > 0 && len(lines) > 0 {
+		fmt.Fprintln(bw)
+	}
+	for _, perFile := range f.PerFile {
+		patterns := strings.Join(perFile.Patterns, ",")
+		directives := ownersListDirectives(perFile.OwnersList)
+		if perFile.NoParent {
+			fmt.Fprintf(bw, "per-file %s=%s\n", patterns, directives[0])
+			directives = directives[1:]
+		}
+		if len(directives) > 0 {
+			fmt.Fprintf(bw, "per-file %s=%s\n", patterns, strings.Join(directives, ","))
+		}
+	}
+	return bw.Flush()
+}
+
+// ownersListDirectives returns the directives that make up an owners list.
+func ownersListDirectives(list OwnersList) []string {
+	var directives []string
+	if list.NoParent {
+		directives = append(directives, "set noparent")
+	}
+	if list.Anyone {
+		directives = append(directives, "*")
+	}
+	for _, include := range list.Includes {
+		directives = append(directives, "file://"+include)
+	}
+	for _, owner := range list.Owners {
+		directives = append(directives, owner.String())
+	}
+	return directives
+}
+
+// OwnersTree is a set of OWNERS files spread across a repository, which
+// together determine the ownership of its files.
+type OwnersTree struct {
+	// files maps paths to OWNERS files, including files that are only used as
+	// includes and don't apply to their directories.
+	files map[string]*OwnersFile
+}
+
+// NewOwnersTree creates a tree from a set of OWNERS files. Files named OWNERS
+// apply to their directory, while files with other names may only be
+// included. Every included file must be in the set.
+func NewOwnersTree(files []*OwnersFile) (*OwnersTree, error) {
+	t := &OwnersTree{files: make(map[string]*OwnersFile, len(files))}
+	for _, f := range files {
+		if _, ok := t.files[f.Path]; ok {
+			return nil, fmt.Errorf("duplicate OWNERS file %s", f.Path)
+		}
+		t.files[f.Path] = f
+	}
+
+	for _, f := range files {
+		for _, include := range f.includes() {
+			if _, ok := t.files[include]; !ok {
+				return nil, fmt.Errorf("%s: included file %s not found", f.Path, include)
+			}
+		}
+	}
+	return t, nil
+}
+
+// LoadOwnersTree finds the OWNERS files in the directory tree under root,
+// along with any files they include, and loads them into a tree. Git metadata
+// directories are skipped.
+func LoadOwnersTree(root string, options ...ParseOption) (*OwnersTree, error) {
+	loaded := map[string]bool{}
+	var files []*OwnersFile
+	var pending []string
+
+	load := func(relPath string) error {
+		loaded[relPath] = true
+		file, err := os.Open(filepath.Join(root, filepath.FromSlash(relPath)))
+		if err != nil {
+			return err
+		}
+		defer file.Close()
+
+		f, err := ParseOwnersFile(file, relPath, options...)
+		if err != nil {
+			return fmt.Errorf("%s: %w", relPath, err)
+		}
+		files = append(files, f)
+		pending = append(pending, f.includes()...)
+		return nil
+	}
+
+	err := filepath.WalkDir(root, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			if d.Name() == ".git" {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if d.Name() != OwnersFileName {
+			return nil
+		}
+
+		rel, err := filepath.Rel(root, p)
+		if err != nil {
+			return err
+		}
+		return load(filepath.ToSlash(rel))
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	for len(pending) > 0 {
+		include := pending[0]
+		pending = pending[1:]
+		if loaded[include] {
+			continue
+		}
+		if err := load(include); err != nil {
+			return nil, fmt.Errorf("loading included file: %w", err)
+		}
+	}
+	return NewOwnersTree(files)
+}
+
+// includes returns the paths of every file the OWNERS file includes.
+func (f *OwnersFile) includes() []string {
+	includes := append([]string{}, f.Includes...)
+	for _, perFile := range f.PerFile {
+		includes = append(includes, perFile.Includes...)
+	}
+	return includes
+}
+
+// Files returns the OWNERS files in the tree, sorted by path.
+func (t *OwnersTree) Files() []*OwnersFile {
+	files := make([]*OwnersFile, 0, len(t.files))
+	for _, f := range t.files {
+		files = append(files, f)
+	}
+	sort.Slice(files, func(i, j int) bool {
+		return files[i].Path < files[j].Path
+	})
+	return files
+}
+
+// OwnersMatch describes the ownership of a file according to an OwnersTree.
+type OwnersMatch struct {
+	// Owners lists the owners from every OWNERS file that applies, nearest
+	// first, without duplicates.
+	Owners []Owner
+	// Anyone is true if one of the OWNERS files allows anyone to approve
+	// changes.
+	Anyone bool
+	// Files lists the paths of the OWNERS files that apply, nearest first.
+	Files []string
+}
+
+// Match finds the owners of the path provided, which should be relative to the
+// root of the repository. Starting in the path's directory, it collects owners
+// from the OWNERS file in each directory up to the root, stopping after the
+// first file (or matching per-file line) that says "set noparent". Matching
+// per-file lines are used before the owners of the directory. If nobody owns
+// the path, nil is returned.
+func (t *OwnersTree) Match(filePath string) (*OwnersMatch, error) {
+	filePath = filepath.ToSlash(filePath)
+	m := &OwnersMatch{}
+	if err := t.match(m, filePath, true); err != nil {
+		return nil, err
+	}
+	if len(m.Owners) == 0 && !m.Anyone {
+		return nil, nil
+	}
+	return m, nil
+}
+
+// match adds the owners of filePath to m, optionally including matching
+// per-file owners.
+func (t *OwnersTree) match(m *OwnersMatch, filePath string, perFile bool) error {
+	seen := ownerSet(m.Owners)
+	for dir := parentDir(filePath); ; dir = parentDir(dir) {
+		f, ok := t.files[path.Join(dir, OwnersFileName)]
+		if ok {
+			m.Files = append(m.Files, f.Path)
+
+			// Paths in per-file patterns are relative to the directory
+			rel := filePath
+			if dir != "" {
+				rel = filePath[len(dir)+1:]
+			}
+			noParent := false
+			for _, pf := range f.PerFile {
+				if !perFile {
+					break
+				}
+				matched, err := pf.match(rel)
+				if err != nil {
+					return fmt.Errorf("%s: %w", f.Path, err)
+				}
+				if matched {
+					t.add(m, pf.OwnersList, seen, map[string]bool{})
+					noParent = noParent || pf.NoParent
+				}
+			}
+			if noParent {
+				return nil
+			}
+
+			t.add(m, f.OwnersList, seen, map[string]bool{})
+			if f.NoParent {
+				return nil
+			}
+		}
+		if dir == "" {
+			return nil
+		}
+	}
+}
+
+// add adds the owners in the list to m, following includes. Visited tracks the
+// included files to avoid include cycles.
+func (t *OwnersTree) add(m *OwnersMatch, list OwnersList, seen, visited map[string]bool) {
+	m.Anyone = m.Anyone || list.Anyone
+	for _, owner := range list.Owners {
+		if !seen[owner.String()] {
+			seen[owner.String()] = true
+			m.Owners = append(m.Owners, owner)
+		}
+	}
+
+	// "set noparent" and per-file lines in included files are ignored
+	for _, include := range list.Includes {
+		if visited[include] {
+			continue
+		}
+		visited[include] = true
+		if f, ok := t.files[include]; ok {
+			t.add(m, f.OwnersList, seen, visited)
+		}
+	}
+}
+
+// match tests whether any of the per-file patterns match the path, which is
+// relative to the OWNERS file's directory.
+func (pf PerFileOwners) match(rel string) (bool, error) {
+	for _, pattern := range pf.Patterns {
+		matched, err := path.Match(pattern, rel)
+		if err != nil || matched {
+			return matched, err
+		}
+	}
+	return false, nil
+}
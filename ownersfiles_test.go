@@ -0,0 +1,300 @@
+package codeowners
+
+import (
+	"bytes"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func mustParseOwnersFile(t *testing.T, filePath, content string) *OwnersFile {
+	t.Helper()
+	f, err := ParseOwnersFile(strings.NewReader(content), filePath)
+	require.NoError(t, err)
+	return f
+}
+
+func TestParseOwnersFile(t *testing.T) {
+	f := mustParseOwnersFile(t, "chrome/browser/OWNERS", `
+# Browser owners
+set noparent
+alice@example.com
+@org/browser  # trailing comment
+file://build/COMMON_OWNERS
+include ../OWNERS
+
+per-file *.gn,BUILD=set noparent
+per-file *.gn,BUILD=bob@example.com,file://build/OWNERS
+per-file README.md=*
+`)
+
+	assert.Equal(t, "chrome/browser", f.Dir())
+	assert.True(t, f.NoParent)
+	assert.False(t, f.Anyone)
+	assert.Equal(t, []Owner{
+		{Value: "alice@example.com", Type: EmailOwner},
+		{Value: "org/browser", Type: TeamOwner},
+	}, f.Owners)
+	assert.Equal(t, []string{"build/COMMON_OWNERS", "chrome/OWNERS"}, f.Includes)
+
+	require.Len(t, f.PerFile, 3)
+	assert.Equal(t, PerFileOwners{Patterns: []string{"*.gn", "BUILD"}, OwnersList: OwnersList{NoParent: true}, LineNumber: 9}, f.PerFile[0])
+	assert.Equal(t, []Owner{{Value: "bob@example.com", Type: EmailOwner}}, f.PerFile[1].Owners)
+	assert.Equal(t, []string{"build/OWNERS"}, f.PerFile[1].Includes)
+	assert.True(t, f.PerFile[2].Anyone)
+
+	var buf bytes.Buffer
+	require.NoError(t, WriteOwnersFile(&buf, f))
+	assert.Equal(t, ""+
+		"set noparent\n"+
+		"file://build/COMMON_OWNERS\n"+
+		"file://chrome/OWNERS\n"+
+		"alice@example.com\n"+
+		"@org/browser\n"+
+		"\n"+
+		"per-file *.gn,BUILD=set noparent\n"+
+		"per-file *.gn,BUILD=file://build/OWNERS,bob@example.com\n"+
+		"per-file README.md=*\n", buf.String())
+
+	// Includes without "//" are relative to the OWNERS file's directory
+	f = mustParseOwnersFile(t, "chrome/browser/OWNERS", "file:../common/OWNERS\nper-file *.gn=file:BUILD_OWNERS\n")
+	assert.Equal(t, []string{"chrome/common/OWNERS"}, f.Includes)
+	assert.Equal(t, []string{"chrome/browser/BUILD_OWNERS"}, f.PerFile[0].Includes)
+
+	_, err := ParseOwnersFile(strings.NewReader("per-file foo\n"), "OWNERS")
+	assert.EqualError(t, err, "line 1: per-file line is missing '='")
+	_, err = ParseOwnersFile(strings.NewReader("\nnot an owner\n"), "OWNERS")
+	assert.EqualError(t, err, "line 2: invalid owner format 'not an owner'")
+}
+
+func TestOwnersTreeMatch(t *testing.T) {
+	tree, err := NewOwnersTree([]*OwnersFile{
+		mustParseOwnersFile(t, "OWNERS", "root@example.com\nper-file *.md=docs@example.com\n"),
+		mustParseOwnersFile(t, "build/COMMON_OWNERS", "@org/build\nset noparent\n"),
+		mustParseOwnersFile(t, "src/OWNERS", "@org/eng\nfile://build/COMMON_OWNERS\nper-file *.gn=set noparent\nper-file *.gn=@org/gn\n"),
+		mustParseOwnersFile(t, "src/net/OWNERS", "set noparent\n@org/net\n"),
+		mustParseOwnersFile(t, "third_party/OWNERS", "*\n"),
+	})
+	require.NoError(t, err)
+
+	tests := []struct {
+		path   string
+		owners []string
+		anyone bool
+		files  []string
+	}{
+		{"README.md", []string{"docs@example.com", "root@example.com"}, false, []string{"OWNERS"}},
+		{"docs/guide.md", []string{"root@example.com"}, false, []string{"OWNERS"}},
+		// Includes add owners, but their "set noparent" lines are ignored
+		{"src/main.go", []string{"@org/eng", "@org/build", "root@example.com"}, false, []string{"src/OWNERS", "OWNERS"}},
+		{"src/BUILD.gn", []string{"@org/gn"}, false, []string{"src/OWNERS"}},
+		{"src/net/socket.go", []string{"@org/net"}, false, []string{"src/net/OWNERS"}},
+		{"src/util/BUILD.gn", []string{"@org/eng", "@org/build", "root@example.com"}, false, []string{"src/OWNERS", "OWNERS"}},
+		{"third_party/lib/lib.c", []string{"root@example.com"}, true, []string{"third_party/OWNERS", "OWNERS"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			m, err := tree.Match(tt.path)
+			require.NoError(t, err)
+			require.NotNil(t, m)
+			assert.Equal(t, tt.owners, ownerStrings(m.Owners))
+			assert.Equal(t, tt.anyone, m.Anyone)
+			assert.Equal(t, tt.files, m.Files)
+		})
+	}
+
+	tree, err = NewOwnersTree([]*OwnersFile{mustParseOwnersFile(t, "src/OWNERS", "@org/eng\n")})
+	require.NoError(t, err)
+	m, err := tree.Match("README.md")
+	require.NoError(t, err)
+	assert.Nil(t, m)
+
+	_, err = NewOwnersTree([]*OwnersFile{mustParseOwnersFile(t, "OWNERS", "file://missing/OWNERS\n")})
+	assert.EqualError(t, err, "OWNERS: included file missing/OWNERS not found")
+}
+
+func TestLoadOwnersTree(t *testing.T) {
+	root := t.TempDir()
+	writeTestFile(t, filepath.Join(root, "OWNERS"), "root@example.com\n")
+	writeTestFile(t, filepath.Join(root, "lib", "OWNERS"), "file://common/OWNERS_LIB\n")
+	writeTestFile(t, filepath.Join(root, "common", "OWNERS_LIB"), "lib@example.com\n")
+	writeTestFile(t, filepath.Join(root, ".git", "OWNERS"), "not an owner\n")
+
+	tree, err := LoadOwnersTree(root)
+	require.NoError(t, err)
+
+	var paths []string
+	for _, f := range tree.Files() {
+		paths = append(paths, f.Path)
+	}
+	assert.Equal(t, []string{"OWNERS", "common/OWNERS_LIB", "lib/OWNERS"}, paths)
+
+	// Included files don't apply to their own directory
+	m, err := tree.Match("common/x.go")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"root@example.com"}, ownerStrings(m.Owners))
+	m, err = tree.Match("lib/x.go")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"lib@example.com", "root@example.com"}, ownerStrings(m.Owners))
+}
+
+func TestOwnersTreeRuleset(t *testing.T) {
+	tree, err := NewOwnersTree([]*OwnersFile{
+		mustParseOwnersFile(t, "OWNERS", "root@example.com\nper-file *.md=docs@example.com\n"),
+		mustParseOwnersFile(t, "src/OWNERS", "@org/eng\nper-file *.gn=set noparent\nper-file *.gn=@org/gn\nper-file [ab].go=a@example.com\n"),
+		mustParseOwnersFile(t, "src/util/OWNERS", "root@example.com\n"),
+		mustParseOwnersFile(t, "src/net/OWNERS", "set noparent\n@org/net\n"),
+		mustParseOwnersFile(t, "docs/OWNERS", "*\n"),
+		mustParseOwnersFile(t, "docs/api/OWNERS", "api@example.com\nper-file v1/*=old@example.com\n"),
+	})
+	require.NoError(t, err)
+
+	ruleset, warnings, err := tree.Ruleset()
+	require.NoError(t, err)
+
+	var rules []string
+	for _, rule := range ruleset {
+		rules = append(rules, rule.String())
+	}
+	assert.Equal(t, []string{
+		"* root@example.com",
+		"/*.md docs@example.com root@example.com",
+		"/docs/",
+		// Anyone may approve changes to docs, and that's inherited
+		"/docs/api/v1/*",
+		"/src/ @org/eng root@example.com",
+		"/src/*.gn @org/gn",
+		"/src/net/ @org/net",
+	}, rules)
+	assert.Equal(t, []string{
+		`docs/api/OWNERS line 2: per-file pattern "v1/*" matches files in subdirectories, whose own OWNERS files won't be combined with it`,
+		`src/OWNERS line 4: per-file pattern "[ab].go" uses a character class, which CODEOWNERS doesn't support`,
+	}, warnings)
+}
+
+func TestOwnersTreeFromRuleset(t *testing.T) {
+	ruleset := mustParseFile(t, strings.Join([]string{
+		"* @org/everyone",
+		"/src/net/ @org/old-net",
+		"*.md @org/docs",
+		"/src/ @org/eng",
+		"/src/*.gn @org/gn",
+		"/src/BUILD @org/build",
+		"/src/BUILD @org/gn",
+		"/src/**/test/ @org/qa",
+		"/src/net/ @org/net",
+		"/vendor/",
+		"/docs/[draft].txt @org/docs",
+	}, "\n"))
+
+	tree, warnings, err := OwnersTreeFromRuleset(ruleset)
+	require.NoError(t, err)
+	assert.Equal(t, []string{
+		`line 3: skipping "*.md": pattern matches at any depth`,
+		`line 2: dropping "/src/net/" as it's overridden by "/src/" on line 4`,
+		`line 6: dropping "/src/BUILD" as it's overridden by line 7`,
+		`line 8: skipping "/src/**/test/": pattern uses "**"`,
+	}, warnings)
+
+	var files []string
+	for _, f := range tree.Files() {
+		var buf bytes.Buffer
+		require.NoError(t, WriteOwnersFile(&buf, f))
+		files = append(files, f.Path+":\n"+buf.String())
+	}
+	assert.Equal(t, []string{
+		"OWNERS:\n@org/everyone\n",
+		"docs/OWNERS:\nper-file \\[draft\\].txt=set noparent\nper-file \\[draft\\].txt=@org/docs\n",
+		// Patterns without wildcards could be directories as well as files
+		"docs/[draft].txt/OWNERS:\nset noparent\n@org/docs\n",
+		"src/BUILD/OWNERS:\nset noparent\n@org/gn\n",
+		"src/OWNERS:\nset noparent\n@org/eng\n\nper-file *.gn=set noparent\nper-file *.gn=@org/gn\nper-file BUILD=set noparent\nper-file BUILD=@org/gn\n",
+		"src/net/OWNERS:\nset noparent\n@org/net\n",
+		"vendor/OWNERS:\nset noparent\n",
+	}, files)
+
+	for path, expected := range map[string][]string{
+		"README.md":         {"@org/everyone"},
+		"src/main.go":       {"@org/eng"},
+		"src/BUILD":         {"@org/gn"},
+		"src/net/socket.go": {"@org/net"},
+		"docs/[draft].txt":  {"@org/docs"},
+		"docs/draft.txt":    {"@org/everyone"},
+	} {
+		m, err := tree.Match(path)
+		require.NoError(t, err)
+		require.NotNil(t, m, path)
+		assert.Equal(t, expected, ownerStrings(m.Owners), path)
+	}
+	m, err := tree.Match("vendor/lib.go")
+	require.NoError(t, err)
+	assert.Nil(t, m)
+}
+
+func TestOwnersTreeFromRulesetDirectoryContents(t *testing.T) {
+	ruleset := mustParseFile(t, "* @org/everyone\n/src/* @org/src\n/docs @org/docs\n")
+
+	tree, warnings, err := OwnersTreeFromRuleset(ruleset)
+	require.NoError(t, err)
+	assert.Empty(t, warnings)
+
+	var files []string
+	for _, f := range tree.Files() {
+		var buf bytes.Buffer
+		require.NoError(t, WriteOwnersFile(&buf, f))
+		files = append(files, f.Path+":\n"+buf.String())
+	}
+	assert.Equal(t, []string{
+		"OWNERS:\n@org/everyone\n\nper-file docs=set noparent\nper-file docs=@org/docs\n",
+		"docs/OWNERS:\nset noparent\n@org/docs\n",
+		"src/OWNERS:\nper-file *=set noparent\nper-file *=@org/src\n",
+	}, files)
+
+	// The owners agree with the CODEOWNERS rules
+	for _, path := range []string{"README.md", "src/main.go", "src/net/socket.go", "docs", "docs/guide/intro.md"} {
+		rule, err := ruleset.Match(path)
+		require.NoError(t, err)
+		m, err := tree.Match(path)
+		require.NoError(t, err)
+		require.NotNil(t, m, path)
+		assert.Equal(t, ownerStrings(rule.Owners), ownerStrings(m.Owners), path)
+	}
+}
+
+func TestOwnersTreeFromRulesetGitLab(t *testing.T) {
+	ruleset, err := ParseFile(strings.NewReader(strings.Join([]string{
+		"* @everyone",
+		"!/docs/",
+		"docs/api @api",
+		"/src/[ab].go @src",
+		"/lib/*.{js,ts} @web",
+		"/ok/ @ok",
+	}, "\n")), WithDialect(GitLab))
+	require.NoError(t, err)
+
+	tree, warnings, err := OwnersTreeFromRuleset(ruleset)
+	require.NoError(t, err)
+	assert.Equal(t, []string{
+		`line 2: skipping "!/docs/": exclusions can't be expressed by OWNERS files`,
+		`line 3: skipping "docs/api": pattern matches at any depth`,
+		`line 4: skipping "/src/[ab].go": pattern uses a character class`,
+		`line 5: skipping "/lib/*.{js,ts}": pattern uses braces`,
+	}, warnings)
+
+	var paths []string
+	for _, f := range tree.Files() {
+		paths = append(paths, f.Path)
+	}
+	assert.Equal(t, []string{"OWNERS", "ok/OWNERS"}, paths)
+}
+
+func ownerStrings(owners []Owner) []string {
+	strs := make([]string, len(owners))
+	for i, o := range owners {
+		strs[i] = o.String()
+	}
+	return strs
+}
@@ -8,20 +8,86 @@ import (
 	"io"
 	"regexp"
 	"strings"
+	"unicode"
+	"unicode/utf8"
 )
 
-type parseOption func(*parseOptions)
+// ParseOption configures how ParseFile and the functions that load CODEOWNERS
+// files parse them.
+type ParseOption func(*parseOptions)
 
 type parseOptions struct {
-	ownerMatchers []OwnerMatcher
+	ownerMatchers    []OwnerMatcher
+	dialect          Dialect
+	skipInvalidLines bool
+	diagnostics      *[]Diagnostic
+	caseInsensitive  bool
+	// extendedPatterns is nil to use the dialect's default
+	extendedPatterns *bool
+	normalizeUnicode bool
 }
 
-func WithOwnerMatchers(mm []OwnerMatcher) parseOption {
+func WithOwnerMatchers(mm []OwnerMatcher) ParseOption {
 	return func(opts *parseOptions) {
 		opts.ownerMatchers = mm
 	}
 }
 
+// WithSkipInvalidLines makes ParseFile skip lines it can't parse rather than
+// failing, which is what GitHub does, so ownership is worked out from the rest
+// of the file. If diagnostics isn't nil, a diagnostic is appended to it for
+// each line that's skipped.
+func WithSkipInvalidLines(diagnostics *[]Diagnostic) ParseOption {
+	return func(opts *parseOptions) {
+		opts.skipInvalidLines = true
+		opts.diagnostics = diagnostics
+	}
+}
+
+// WithIgnoreCase sets whether the rules' patterns match paths regardless of
+// case, as on macOS and Windows file systems. Matching is case-sensitive by
+// default.
+func WithIgnoreCase(ignoreCase bool) ParseOption {
+	return func(opts *parseOptions) {
+		opts.caseInsensitive = ignoreCase
+	}
+}
+
+// WithExtendedPatterns sets whether the rules' patterns support character
+// classes, like "[a-z]", "[!abc]", and "[[:digit:]]", and brace expansion, like
+// "*.{js,ts}", as .gitignore files and other ownership tools do. By default
+// they're only supported by the GitLab dialect, and square brackets and braces
+// match themselves. This option overrides the dialect's default either way.
+func WithExtendedPatterns(enabled bool) ParseOption {
+	return func(opts *parseOptions) {
+		opts.extendedPatterns = &enabled
+	}
+}
+
+// WithUnicodeNormalization sets whether the rules' patterns match paths
+// regardless of their Unicode normalization form. For example, "é" can be
+// written as one code point (NFC) or as "e" followed by a combining accent
+// (NFD), which macOS file systems tend to produce. When enabled, patterns and
+// paths are both converted to NFC before matching, so either form matches the
+// other.
+func WithUnicodeNormalization(enabled bool) ParseOption {
+	return func(opts *parseOptions) {
+		opts.normalizeUnicode = enabled
+	}
+}
+
+// patternOptions returns the options for compiling patterns.
+func (opts parseOptions) patternOptions() patternOptions {
+	patternOpts := opts.dialect.patternOptions()
+	patternOpts.caseInsensitive = opts.caseInsensitive
+	patternOpts.normalizeUnicode = opts.normalizeUnicode
+	if opts.extendedPatterns != nil {
+		patternOpts.charClasses = *opts.extendedPatterns
+		patternOpts.braces = *opts.extendedPatterns
+	}
+	return patternOpts
+}
+
 type OwnerMatcher interface {
 	// Matches give string agains a pattern e.g. a regexp.
 	// Should return ErrNoMatch if the pattern doesn't match.
@@ -97,15 +163,20 @@ func MatchUsernameOwner(s string) (Owner, error) {
 
 // ParseFile parses a CODEOWNERS file, returning a set of rules.
 // To override the default owner matchers, pass WithOwnerMatchers() as an option.
-func ParseFile(f io.Reader, options ...parseOption) (Ruleset, error) {
-	opts := parseOptions{ownerMatchers: DefaultOwnerMatchers}
+// Files are parsed as the GitHub dialect unless WithDialect() is passed.
+func ParseFile(f io.Reader, options ...ParseOption) (Ruleset, error) {
+	opts := parseOptions{dialect: GitHub}
 	for _, opt := range options {
 		opt(&opts)
 	}
+	if opts.ownerMatchers == nil {
+		opts.ownerMatchers = opts.dialect.OwnerMatchers()
+	}
 
 	rules := Ruleset{}
 	scanner := bufio.NewScanner(f)
 	lineNo := 0
+	var section *Section
 	for scanner.Scan() {
 		lineNo++
 		line := strings.TrimSpace(scanner.Text())
@@ -115,16 +186,51 @@ func ParseFile(f io.Reader, options ...parseOption) (Ruleset, error) {
 			continue
 		}
 
+		// GitLab section headers apply to the rules that follow them
+		if opts.dialect == GitLab && isSectionHeader(line) {
+			s, err := parseSection(line, opts)
+			if err != nil {
+				if opts.skipLine(lineNo, err) {
+					continue
+				}
+				return nil, fmt.Errorf("line %d: %w", lineNo, err)
+			}
+			s.LineNumber = lineNo
+			section = s
+			continue
+		}
+
 		rule, err := parseRule(line, opts)
 		if err != nil {
+			if opts.skipLine(lineNo, err) {
+				continue
+			}
 			return nil, fmt.Errorf("line %d: %w", lineNo, err)
 		}
 		rule.LineNumber = lineNo
+		if section != nil {
+			rule.Section = section
+			if len(rule.Owners) == 0 && !rule.Excluded {
+				rule.Owners = section.Owners
+			}
+		}
 		rules = append(rules, rule)
 	}
 	return rules, nil
 }
 
+// skipLine records a line that couldn't be parsed if invalid lines are being
+// skipped, and reports whether it should be skipped.
+func (opts parseOptions) skipLine(lineNo int, err error) bool {
+	if !opts.skipInvalidLines {
+		return false
+	}
+	if opts.diagnostics != nil {
+		*opts.diagnostics = append(*opts.diagnostics, Diagnostic{LineNumber: lineNo, Message: err.Error()})
+	}
+	return true
+}
+
 const (
 	statePattern = iota + 1
 	stateOwners
@@ -133,11 +239,16 @@ const (
 // parseRule parses a single line of a CODEOWNERS file, returning a Rule struct
 func parseRule(ruleStr string, opts parseOptions) (Rule, error) {
 	r := Rule{}
+	patternOpts := opts.patternOptions()
 
 	state := statePattern
 	escaped := false
 	buf := bytes.Buffer{}
+	// Positions in errors are counted in characters rather than bytes, so they
+	// point at the right column in lines with non-ASCII characters
+	pos := 0
 	for i, ch := range strings.TrimSpace(ruleStr) {
+		pos++
 		// Comments consume the rest of the line and stop further parsing
 		if ch == '#' {
 			r.Comment = strings.TrimSpace(ruleStr[i+1:])
@@ -147,6 +258,11 @@ func parseRule(ruleStr string, opts parseOptions) (Rule, error) {
 		switch state {
 		case statePattern:
 			switch {
+			case ch == '!' && i == 0 && opts.dialect == GitLab:
+				// GitLab excludes paths matching patterns that start with "!"
+				r.Excluded = true
+				continue
+
 			case ch == '\\':
 				// Escape the next character (important for whitespace while parsing), but
 				// don't lose the backslash as it's part of the pattern
@@ -156,7 +272,7 @@ func parseRule(ruleStr string, opts parseOptions) (Rule, error) {
 
 			case isWhitespace(ch) && !escaped:
 				// Unescaped whitespace means this is the end of the pattern
-				pattern, err := newPattern(buf.String())
+				pattern, err := newPattern(buf.String(), patternOpts)
 				if err != nil {
 					return r, err
 				}
@@ -164,12 +280,16 @@ func parseRule(ruleStr string, opts parseOptions) (Rule, error) {
 				buf.Reset()
 				state = stateOwners
 
+			case ch == '!' && i == 0:
+				// GitHub doesn't support negation, so it skips these lines
+				return r, fmt.Errorf("unexpected character '%c' at position %d", ch, pos)
+
 			case isPatternChar(ch) || escaped:
 				// Keep any valid pattern characters and escaped characters
 				buf.WriteRune(ch)
 
 			default:
-				return r, fmt.Errorf("unexpected character '%c' at position %d", ch, i+1)
+				return r, fmt.Errorf("unexpected character '%c' at position %d", ch, pos)
 			}
 			// Escaping only applies to one character
 			escaped = false
@@ -183,7 +303,7 @@ func parseRule(ruleStr string, opts parseOptions) (Rule, error) {
 					ownerStr := buf.String()
 					owner, err := newOwner(ownerStr, opts.ownerMatchers)
 					if err != nil {
-						return r, fmt.Errorf("%w at position %d", err, i+1-len(ownerStr))
+						return r, fmt.Errorf("%w at position %d", err, pos-utf8.RuneCountInString(ownerStr))
 					}
 					r.Owners = append(r.Owners, owner)
 					buf.Reset()
@@ -194,7 +314,7 @@ func parseRule(ruleStr string, opts parseOptions) (Rule, error) {
 				buf.WriteRune(ch)
 
 			default:
-				return r, fmt.Errorf("unexpected character '%c' at position %d", ch, i+1)
+				return r, fmt.Errorf("unexpected character '%c' at position %d", ch, pos)
 			}
 		}
 	}
@@ -207,7 +327,7 @@ func parseRule(ruleStr string, opts parseOptions) (Rule, error) {
 			return r, fmt.Errorf("unexpected end of rule")
 		}
 
-		pattern, err := newPattern(buf.String())
+		pattern, err := newPattern(buf.String(), patternOpts)
 		if err != nil {
 			return r, err
 		}
@@ -219,12 +339,16 @@ func parseRule(ruleStr string, opts parseOptions) (Rule, error) {
 			ownerStr := buf.String()
 			owner, err := newOwner(ownerStr, opts.ownerMatchers)
 			if err != nil {
-				return r, fmt.Errorf("%s at position %d", err.Error(), len(ruleStr)+1-len(ownerStr))
+				return r, fmt.Errorf("%s at position %d", err.Error(), utf8.RuneCountInString(ruleStr)+1-utf8.RuneCountInString(ownerStr))
 			}
 			r.Owners = append(r.Owners, owner)
 		}
 	}
 
+	if r.Excluded && len(r.Owners) > 0 {
+		return r, fmt.Errorf("excluded patterns can't have owners")
+	}
+
 	return r, nil
 }
 
@@ -254,13 +378,12 @@ func isAlphanumeric(ch rune) bool {
 	return (ch >= 'A' && ch <= 'Z') || (ch >= 'a' && ch <= 'z') || (ch >= '0' && ch <= '9')
 }
 
-// isPatternChar matches characters that are allowed in patterns
+// isPatternChar matches characters that are allowed in patterns, which is any
+// character that can appear in a path other than control characters. Unescaped
+// whitespace ends the pattern and "#" starts a comment, so they're handled
+// separately.
 func isPatternChar(ch rune) bool {
-	switch ch {
-	case '*', '?', '.', '/', '@', '_', '+', '-', ':', '\\', '(', ')', '|', '{', '}', '[', ']', '~', '^':
-		return true
-	}
-	return isAlphanumeric(ch)
+	return ch != utf8.RuneError && !unicode.IsControl(ch)
 }
 
 // isOwnersChar matches characters that are allowed in owner definitions
@@ -305,8 +305,57 @@ func TestParseRule(t *testing.T) {
 				Owners:  []Owner{{Value: "org/team", Type: "team"}},
 			},
 		},
+		{
+			name: "pattern with non-ASCII characters",
+			rule: "docs/日本語/ @org/team",
+			expected: Rule{
+				pattern: mustBuildPattern(t, "docs/日本語/"),
+				Owners:  []Owner{{Value: "org/team", Type: "team"}},
+			},
+		},
+		{
+			name: "pattern with special characters",
+			rule: "a=b,c'd&e$f%g!h.txt @org/team",
+			expected: Rule{
+				pattern: mustBuildPattern(t, "a=b,c'd&e$f%g!h.txt"),
+				Owners:  []Owner{{Value: "org/team", Type: "team"}},
+			},
+		},
+		{
+			name: "pattern with escaped leading exclamation mark",
+			rule: "\\!important.txt @org/team",
+			expected: Rule{
+				pattern: mustBuildPattern(t, "\\!important.txt"),
+				Owners:  []Owner{{Value: "org/team", Type: "team"}},
+			},
+		},
 
 		// Error cases
+		{
+			name: "negated pattern",
+			rule: "!file.txt @user",
+			err:  "unexpected character '!' at position 1",
+		},
+		{
+			name: "pattern with control character",
+			rule: "file\x7f.txt @user",
+			err:  "unexpected character '\x7f' at position 5",
+		},
+		{
+			name: "non-ASCII pattern with malformed owners",
+			rule: "docs/日本語/ missing-at-sign",
+			err:  "invalid owner format 'missing-at-sign' at position 11",
+		},
+		{
+			name: "non-ASCII pattern with malformed owner followed by another",
+			rule: "docs/日本語/ missing-at-sign @user",
+			err:  "invalid owner format 'missing-at-sign' at position 11",
+		},
+		{
+			name: "non-ASCII pattern with control character",
+			rule: "日本\x7f.txt @user",
+			err:  "unexpected character '\x7f' at position 3",
+		},
 		{
 			name: "empty rule",
 			rule: "",
@@ -363,10 +412,99 @@ func TestParseRule(t *testing.T) {
 	}
 }
 
-func mustBuildPattern(t *testing.T, pat string) pattern {
-	p, err := newPattern(pat)
+func TestParseFileSkipInvalidLines(t *testing.T) {
+	file := "* @org/everyone\n/docs/ docs-team\n!/vendor/\n/src/ @alice\n"
+
+	_, err := ParseFile(strings.NewReader(file))
+	assert.EqualError(t, err, "line 2: invalid owner format 'docs-team' at position 8")
+
+	var diagnostics []Diagnostic
+	ruleset, err := ParseFile(strings.NewReader(file), WithSkipInvalidLines(&diagnostics))
+	assert.NoError(t, err)
+	assert.Equal(t, []Diagnostic{
+		{LineNumber: 2, Message: "invalid owner format 'docs-team' at position 8"},
+		{LineNumber: 3, Message: "unexpected character '!' at position 1"},
+	}, diagnostics)
+
+	// The skipped line would have made docs unowned, so the first rule applies
+	rule, err := ruleset.Match("docs/index.md")
+	assert.NoError(t, err)
+	if assert.NotNil(t, rule) {
+		assert.Equal(t, 1, rule.LineNumber)
+	}
+	assert.Len(t, ruleset, 2)
+
+	// Diagnostics are optional
+	ruleset, err = ParseFile(strings.NewReader(file), WithSkipInvalidLines(nil))
+	assert.NoError(t, err)
+	assert.Len(t, ruleset, 2)
+}
+
+func mustBuildPattern(t *testing.T, pat string) Pattern {
+	p, err := newPattern(pat, patternOptions{})
 	if err != nil {
 		t.Fatal(err)
 	}
 	return p
 }
+
+func TestParseFileIgnoreCase(t *testing.T) {
+	ruleset, err := ParseFile(strings.NewReader("/Docs/ @docs\n"), WithIgnoreCase(true))
+	assert.NoError(t, err)
+	rule, err := ruleset.Match("docs/index.md")
+	assert.NoError(t, err)
+	assert.NotNil(t, rule)
+
+	ruleset, err = ParseFile(strings.NewReader("/Docs/ @docs\n"), WithIgnoreCase(false))
+	assert.NoError(t, err)
+	rule, err = ruleset.Match("docs/index.md")
+	assert.NoError(t, err)
+	assert.Nil(t, rule)
+}
+
+func TestParseFileExtendedPatterns(t *testing.T) {
+	file := "*.{js,ts} @frontend\n/v[!0]/ @api\n"
+
+	// Square brackets and braces are literal by default
+	ruleset, err := ParseFile(strings.NewReader(file))
+	assert.NoError(t, err)
+	rule, err := ruleset.Match("web/app.ts")
+	assert.NoError(t, err)
+	assert.Nil(t, rule)
+
+	for _, options := range [][]ParseOption{{WithExtendedPatterns(true)}, {WithDialect(GitLab)}} {
+		ruleset, err := ParseFile(strings.NewReader(file), options...)
+		assert.NoError(t, err)
+		rule, err := ruleset.Match("web/app.ts")
+		assert.NoError(t, err)
+		assert.NotNil(t, rule)
+		rule, err = ruleset.Match("v1/api.go")
+		assert.NoError(t, err)
+		assert.NotNil(t, rule)
+	}
+
+	// Passing false overrides the dialect's default
+	ruleset, err = ParseFile(strings.NewReader("/v[12]/ @api\n"), WithDialect(GitLab), WithExtendedPatterns(false))
+	assert.NoError(t, err)
+	rule, err = ruleset.Match("v1/api.go")
+	assert.NoError(t, err)
+	assert.Nil(t, rule)
+	rule, err = ruleset.Match("v[12]/api.go")
+	assert.NoError(t, err)
+	assert.NotNil(t, rule)
+}
+
+func TestParseFileUnicodeNormalization(t *testing.T) {
+	file := "/docs/caf\u00e9/ @docs\n"
+	ruleset, err := ParseFile(strings.NewReader(file), WithUnicodeNormalization(true))
+	assert.NoError(t, err)
+	rule, err := ruleset.Match("docs/cafe\u0301/menu.md")
+	assert.NoError(t, err)
+	assert.NotNil(t, rule)
+
+	ruleset, err = ParseFile(strings.NewReader(file))
+	assert.NoError(t, err)
+	rule, err = ruleset.Match("docs/cafe\u0301/menu.md")
+	assert.NoError(t, err)
+	assert.Nil(t, rule)
+}
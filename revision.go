@@ -0,0 +1,137 @@
+package codeowners
+
+import (
+	"bytes"
+	"fmt"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+// LoadFileAtRevision loads and parses the CODEOWNERS file at the path specified
+// as it was at a revision, which may be anything git understands as a commit
+// (e.g. a commit hash, branch, tag, or HEAD~2). The path is relative to the
+// root of the repository. Reading revisions requires the git binary. As with
+// LoadFile, the dialect is detected from the path unless WithDialect is given.
+func (r *Repository) LoadFileAtRevision(rev, path string, options ...ParseOption) (Ruleset, error) {
+	contents, err := r.ReadFileAtRevision(rev, path)
+	if err != nil {
+		return nil, err
+	}
+	return ParseFile(bytes.NewReader(contents), append([]ParseOption{WithDialect(DetectDialect(path))}, options...)...)
+}
+
+// LoadFileFromStandardLocationAtRevision loads and parses the CODEOWNERS file
+// at one of the standard locations as it was at a revision. See
+// LoadFileAtRevision for details.
+func (r *Repository) LoadFileFromStandardLocationAtRevision(rev string, options ...ParseOption) (Ruleset, error) {
+	if err := checkRevision(rev); err != nil {
+		return nil, err
+	}
+	path, err := r.FindFileAtStandardLocation(rev)
+	if err != nil {
+		return nil, err
+	}
+	if path == "" {
+		return nil, fmt.Errorf("could not find CODEOWNERS file at any of the standard locations at revision %s", rev)
+	}
+	return r.LoadFileAtRevision(rev, path, options...)
+}
+
+// FindFileAtStandardLocation returns the path of the CODEOWNERS file at the
+// first of the standard locations that holds one, relative to the root of the
+// repository. If rev is empty, the work tree is searched; otherwise the tree
+// of that revision is. An empty string is returned if there's no CODEOWNERS
+// file at any of the standard locations.
+func (r *Repository) FindFileAtStandardLocation(rev string) (string, error) {
+	if rev == "" {
+		for _, path := range standardLocations {
+			if fileExists(filepath.Join(r.Root, path)) {
+				return path, nil
+			}
+		}
+		return "", nil
+	}
+
+	if err := checkRevision(rev); err != nil {
+		return "", err
+	}
+	args := append([]string{"ls-tree", "-z", rev, "--"}, standardLocations...)
+	output, err := r.git(args...)
+	if err != nil {
+		return "", err
+	}
+
+	// Entries are formatted as "<mode> <type> <object>\t<path>"
+	blobs := map[string]bool{}
+	for _, entry := range splitNul(output) {
+		info, path, _ := strings.Cut(entry, "\t")
+		if fields := strings.Fields(info); len(fields) == 3 && fields[1] == "blob" {
+			blobs[path] = true
+		}
+	}
+
+	for _, path := range standardLocations {
+		if blobs[path] {
+			return path, nil
+		}
+	}
+	return "", nil
+}
+
+// ReadFileAtRevision returns the contents of the file at path as it was at a
+// revision. The path is relative to the root of the repository.
+func (r *Repository) ReadFileAtRevision(rev, path string) ([]byte, error) {
+	if err := checkRevision(rev); err != nil {
+		return nil, err
+	}
+	return r.git("cat-file", "blob", rev+":"+strings.TrimPrefix(path, "/"))
+}
+
+// FilesAtRevision lists the paths of the files in the tree of a revision,
+// relative to the root of the repository.
+func (r *Repository) FilesAtRevision(rev string) ([]string, error) {
+	if err := checkRevision(rev); err != nil {
+		return nil, err
+	}
+	output, err := r.git("ls-tree", "-r", "-z", "--name-only", "--full-tree", rev)
+	if err != nil {
+		return nil, err
+	}
+	return splitNul(output), nil
+}
+
+// checkRevision rejects revisions that git would interpret as options.
+func checkRevision(rev string) error {
+	if rev == "" || strings.HasPrefix(rev, "-") {
+		return fmt.Errorf("invalid revision %q", rev)
+	}
+	return nil
+}
+
+// git runs a git command at the root of the repository's work tree, returning
+// its standard output. Errors include anything git wrote to standard error.
+func (r *Repository) git(args ...string) ([]byte, error) {
+	cmd := exec.Command("git", append([]string{"-C", r.Root}, args...)...)
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	output, err := cmd.Output()
+	if err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return nil, fmt.Errorf("git %s: %s", args[0], msg)
+		}
+		return nil, fmt.Errorf("git %s: %w", args[0], err)
+	}
+	return output, nil
+}
+
+// splitNul splits NUL-terminated git output into its fields.
+func splitNul(output []byte) []string {
+	var fields []string
+	for _, field := range bytes.Split(output, []byte{0}) {
+		if len(field) > 0 {
+			fields = append(fields, string(field))
+		}
+	}
+	return fields
+}
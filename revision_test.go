@@ -0,0 +1,72 @@
+package codeowners
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoadFileAtRevision(t *testing.T) {
+	root := initTestRepository(t)
+	writeTestFile(t, filepath.Join(root, "CODEOWNERS"), "* @old-owner\n")
+	writeTestFile(t, filepath.Join(root, "src", "main.go"), "package main\n")
+	runGit(t, root, "add", ".")
+	runGit(t, root, "commit", "--quiet", "-m", "Initial commit")
+	runGit(t, root, "tag", "v1")
+
+	// Move the file to a location with higher precedence and change the owner
+	runGit(t, root, "rm", "--quiet", "CODEOWNERS")
+	writeTestFile(t, filepath.Join(root, ".github", "CODEOWNERS"), "* @new-owner\n")
+	writeTestFile(t, filepath.Join(root, "docs", "README.md"), "# Docs\n")
+	runGit(t, root, "add", ".")
+	runGit(t, root, "commit", "--quiet", "-m", "Change owners")
+
+	repo, err := FindRepository(root)
+	require.NoError(t, err)
+
+	ruleset, err := repo.LoadFileFromStandardLocationAtRevision("v1")
+	require.NoError(t, err)
+	assertOwner(t, ruleset, "src/main.go", "@old-owner")
+
+	ruleset, err = repo.LoadFileFromStandardLocationAtRevision("HEAD")
+	require.NoError(t, err)
+	assertOwner(t, ruleset, "src/main.go", "@new-owner")
+
+	ruleset, err = repo.LoadFileAtRevision("HEAD~1", "CODEOWNERS")
+	require.NoError(t, err)
+	assertOwner(t, ruleset, "src/main.go", "@old-owner")
+
+	_, err = repo.LoadFileAtRevision("HEAD", "CODEOWNERS")
+	assert.Error(t, err)
+
+	files, err := repo.FilesAtRevision("v1")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"CODEOWNERS", "src/main.go"}, files)
+
+	files, err = repo.FilesAtRevision("HEAD")
+	require.NoError(t, err)
+	assert.Equal(t, []string{".github/CODEOWNERS", "docs/README.md", "src/main.go"}, files)
+}
+
+func TestLoadFileAtRevisionInvalidRevision(t *testing.T) {
+	root := initTestRepository(t)
+	repo, err := FindRepository(root)
+	require.NoError(t, err)
+
+	_, err = repo.LoadFileFromStandardLocationAtRevision("--output=/tmp/x")
+	assert.EqualError(t, err, `invalid revision "--output=/tmp/x"`)
+
+	_, err = repo.FilesAtRevision("does-not-exist")
+	assert.Error(t, err)
+}
+
+func assertOwner(t *testing.T, ruleset Ruleset, path, owner string) {
+	t.Helper()
+	rule, err := ruleset.Match(path)
+	require.NoError(t, err)
+	require.NotNil(t, rule, "no rule matched %s", path)
+	require.Len(t, rule.Owners, 1)
+	assert.Equal(t, owner, rule.Owners[0].String())
+}
@@ -0,0 +1,186 @@
+package codeowners
+
+import "sort"
+
+const (
+	// RuleAdded is the change kind for rules that only exist in the new ruleset.
+	RuleAdded string = "added"
+	// RuleRemoved is the change kind for rules that only exist in the old
+	// ruleset.
+	RuleRemoved string = "removed"
+	// RuleMoved is the change kind for rules whose position relative to the
+	// other rules changed. As the last matching rule wins, this may change
+	// ownership even if the rule itself is unchanged.
+	RuleMoved string = "moved"
+	// RuleOwnersChanged is the change kind for rules whose owners changed.
+	RuleOwnersChanged string = "owners-changed"
+	// RuleSectionChanged is the change kind for GitLab rules that moved into a
+	// different section, which changes the owners they're combined with.
+	RuleSectionChanged string = "section-changed"
+)
+
+// RuleChange describes a difference between two rulesets at the level of
+// individual rules.
+type RuleChange struct {
+	// Kind will be one of 'added', 'removed', 'moved', 'owners-changed', or
+	// 'section-changed'.
+	Kind string
+	// Pattern is the raw pattern of the rule that changed, with a leading "!"
+	// for excluded rules.
+	Pattern string
+	// OldRule is the rule in the old ruleset, which is nil for added rules.
+	OldRule *Rule
+	// NewRule is the rule in the new ruleset, which is nil for removed rules.
+	NewRule *Rule
+}
+
+// DiffRulesets compares two rulesets rule by rule, independently of any files.
+// Rules are paired up by pattern rather than line number, as line numbers
+// shift whenever lines are added or removed above them. If a pattern appears
+// more than once in a ruleset, occurrences are paired in order. An exclusion
+// is never paired with an ownership rule for the same pattern, and rules are
+// paired within their GitLab section first, so a rule that only exists in a
+// different section of the new ruleset is reported as 'section-changed'. A rule that
+// was both moved and had its owners changed is reported twice, once for each
+// kind of change.
+//
+// Removed rules are listed first, ordered by their line in the old ruleset,
+// followed by the other changes ordered by their line in the new ruleset.
+func DiffRulesets(oldRuleset, newRuleset Ruleset) []RuleChange {
+	// Pair up rules with the same pattern in the same section, matching the
+	// nth occurrence of a pattern in the old ruleset with the nth occurrence
+	// in the new one
+	newIndexes := map[ruleDiffKey][]int{}
+	for i := range newRuleset {
+		key := newRuleDiffKey(&newRuleset[i], true)
+		newIndexes[key] = append(newIndexes[key], i)
+	}
+
+	var removed, changes []RuleChange
+	pairedNew := make([]bool, len(newRuleset))
+	var pairs [][2]int
+	var unpairedOld []int
+	for i := range oldRuleset {
+		key := newRuleDiffKey(&oldRuleset[i], true)
+		candidates := newIndexes[key]
+		if len(candidates) == 0 {
+			unpairedOld = append(unpairedOld, i)
+			continue
+		}
+		newIndexes[key] = candidates[1:]
+		pairedNew[candidates[0]] = true
+		pairs = append(pairs, [2]int{i, candidates[0]})
+	}
+
+	// Rules left over on both sides with the same pattern moved between
+	// sections
+	movedIndexes := map[ruleDiffKey][]int{}
+	for i := range newRuleset {
+		if !pairedNew[i] {
+			key := newRuleDiffKey(&newRuleset[i], false)
+			movedIndexes[key] = append(movedIndexes[key], i)
+		}
+	}
+	for _, i := range unpairedOld {
+		oldRule := &oldRuleset[i]
+		key := newRuleDiffKey(oldRule, false)
+		candidates := movedIndexes[key]
+		if len(candidates) == 0 {
+			removed = append(removed, RuleChange{Kind: RuleRemoved, Pattern: diffPattern(oldRule), OldRule: oldRule})
+			continue
+		}
+		movedIndexes[key] = candidates[1:]
+		pairedNew[candidates[0]] = true
+		newRule := &newRuleset[candidates[0]]
+		changes = append(changes, RuleChange{Kind: RuleSectionChanged, Pattern: diffPattern(newRule), OldRule: oldRule, NewRule: newRule})
+		if ownersKey(oldRule.Owners) != ownersKey(newRule.Owners) {
+			changes = append(changes, RuleChange{Kind: RuleOwnersChanged, Pattern: diffPattern(newRule), OldRule: oldRule, NewRule: newRule})
+		}
+	}
+
+	// Paired rules whose new positions are in increasing order kept their
+	// relative order. Keeping the longest such sequence in place minimises the
+	// number of rules reported as moved.
+	newPositions := make([]int, len(pairs))
+	for i, pair := range pairs {
+		newPositions[i] = pair[1]
+	}
+	inOrder := longestIncreasingSubsequence(newPositions)
+
+	for i, pair := range pairs {
+		oldRule, newRule := &oldRuleset[pair[0]], &newRuleset[pair[1]]
+		if !inOrder[i] {
+			changes = append(changes, RuleChange{Kind: RuleMoved, Pattern: diffPattern(newRule), OldRule: oldRule, NewRule: newRule})
+		}
+		if ownersKey(oldRule.Owners) != ownersKey(newRule.Owners) {
+			changes = append(changes, RuleChange{Kind: RuleOwnersChanged, Pattern: diffPattern(newRule), OldRule: oldRule, NewRule: newRule})
+		}
+	}
+
+	for i := range newRuleset {
+		if !pairedNew[i] {
+			newRule := &newRuleset[i]
+			changes = append(changes, RuleChange{Kind: RuleAdded, Pattern: diffPattern(newRule), NewRule: newRule})
+		}
+	}
+
+	sort.SliceStable(changes, func(i, j int) bool {
+		return changes[i].NewRule.LineNumber < changes[j].NewRule.LineNumber
+	})
+	return append(removed, changes...)
+}
+
+// ruleDiffKey identifies the rules DiffRulesets pairs with each other.
+type ruleDiffKey struct {
+	pattern  string
+	excluded bool
+	section  string
+}
+
+func newRuleDiffKey(rule *Rule, withSection bool) ruleDiffKey {
+	key := ruleDiffKey{pattern: rule.RawPattern(), excluded: rule.Excluded}
+	if withSection {
+		key.section = sectionKey(rule.Section)
+	}
+	return key
+}
+
+// diffPattern returns the pattern as written in the file, including the "!" of
+// excluded rules.
+func diffPattern(rule *Rule) string {
+	if rule.Excluded {
+		return "!" + rule.RawPattern()
+	}
+	return rule.RawPattern()
+}
+
+// longestIncreasingSubsequence returns a mask of the elements of seq that form
+// one of its longest strictly increasing subsequences.
+func longestIncreasingSubsequence(seq []int) []bool {
+	// tails[k] is the index of the smallest possible tail of an increasing
+	// subsequence of length k+1, and prev links each element to its
+	// predecessor in the subsequence it ends
+	tails := []int{}
+	prev := make([]int, len(seq))
+	for i, val := range seq {
+		k := sort.Search(len(tails), func(k int) bool { return seq[tails[k]] >= val })
+		if k > 0 {
+			prev[i] = tails[k-1]
+		} else {
+			prev[i] = -1
+		}
+		if k == len(tails) {
+			tails = append(tails, i)
+		} else {
+			tails[k] = i
+		}
+	}
+
+	mask := make([]bool, len(seq))
+	if len(tails) > 0 {
+		for i := tails[len(tails)-1]; i >= 0; i = prev[i] {
+			mask[i] = true
+		}
+	}
+	return mask
+}
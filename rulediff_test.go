@@ -0,0 +1,117 @@
+package codeowners
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDiffRulesets(t *testing.T) {
+	oldRuleset := mustParseFile(t, strings.Join([]string{
+		"*          @org/everyone",
+		"/legacy/   @org/legacy",
+		"*.md       @org/docs",
+		"/src/      @org/eng",
+		"/src/ui/   @org/frontend",
+	}, "\n"))
+	newRuleset := mustParseFile(t, strings.Join([]string{
+		"# Everything else",
+		"*          @org/everyone",
+		"/src/      @org/eng",
+		"/src/ui/   @org/design @org/frontend",
+		"*.md       @org/docs",
+		"/api/      @org/api",
+	}, "\n"))
+
+	changes := DiffRulesets(oldRuleset, newRuleset)
+
+	type change struct {
+		kind    string
+		pattern string
+		oldLine int
+		newLine int
+	}
+	var actual []change
+	for _, c := range changes {
+		ac := change{kind: c.Kind, pattern: c.Pattern}
+		if c.OldRule != nil {
+			ac.oldLine = c.OldRule.LineNumber
+		}
+		if c.NewRule != nil {
+			ac.newLine = c.NewRule.LineNumber
+		}
+		actual = append(actual, ac)
+	}
+
+	assert.Equal(t, []change{
+		{kind: RuleRemoved, pattern: "/legacy/", oldLine: 2},
+		{kind: RuleOwnersChanged, pattern: "/src/ui/", oldLine: 5, newLine: 4},
+		{kind: RuleMoved, pattern: "*.md", oldLine: 3, newLine: 5},
+		{kind: RuleAdded, pattern: "/api/", newLine: 6},
+	}, actual)
+}
+
+// Adding blank lines and comments shifts line numbers, but isn't a change
+func TestDiffRulesetsIgnoresLineNumbers(t *testing.T) {
+	oldRuleset := mustParseFile(t, "*.go @org/go\n*.js @org/js")
+	newRuleset := mustParseFile(t, "# Go\n*.go @org/go\n\n# JavaScript\n*.js @org/js")
+
+	assert.Empty(t, DiffRulesets(oldRuleset, newRuleset))
+}
+
+func TestDiffRulesetsDuplicatePatterns(t *testing.T) {
+	oldRuleset := mustParseFile(t, "*.go @a\n/src/ @b\n*.go @c")
+	newRuleset := mustParseFile(t, "*.go @a\n/src/ @b")
+
+	changes := DiffRulesets(oldRuleset, newRuleset)
+	if assert.Len(t, changes, 1) {
+		assert.Equal(t, RuleRemoved, changes[0].Kind)
+		assert.Equal(t, 3, changes[0].OldRule.LineNumber)
+	}
+}
+
+func TestDiffRulesetsExclusions(t *testing.T) {
+	parse := func(contents string) Ruleset {
+		ruleset, err := ParseFile(strings.NewReader(contents), WithDialect(GitLab))
+		require.NoError(t, err)
+		return ruleset
+	}
+	oldRuleset := parse("* @org/everyone\n!/docs/")
+	newRuleset := parse("* @org/everyone\n/docs/ @org/docs")
+
+	changes := DiffRulesets(oldRuleset, newRuleset)
+	if assert.Len(t, changes, 2) {
+		assert.Equal(t, RuleRemoved, changes[0].Kind)
+		assert.Equal(t, "!/docs/", changes[0].Pattern)
+		assert.Equal(t, RuleAdded, changes[1].Kind)
+		assert.Equal(t, "/docs/", changes[1].Pattern)
+	}
+}
+
+func TestDiffRulesetsSections(t *testing.T) {
+	parse := func(contents string) Ruleset {
+		ruleset, err := ParseFile(strings.NewReader(contents), WithDialect(GitLab))
+		require.NoError(t, err)
+		return ruleset
+	}
+	oldRuleset := parse("[Docs] @org/docs\n*.md\n[Backend] @org/backend\n*.go\n")
+	newRuleset := parse("[Docs] @org/docs\n*.md\n*.go\n[Backend] @org/backend\n")
+
+	changes := DiffRulesets(oldRuleset, newRuleset)
+	if assert.Len(t, changes, 2) {
+		assert.Equal(t, RuleSectionChanged, changes[0].Kind)
+		assert.Equal(t, "*.go", changes[0].Pattern)
+		assert.Equal(t, "Backend", changes[0].OldRule.Section.Name)
+		assert.Equal(t, "Docs", changes[0].NewRule.Section.Name)
+		assert.Equal(t, RuleOwnersChanged, changes[1].Kind)
+	}
+}
+
+func TestLongestIncreasingSubsequence(t *testing.T) {
+	assert.Equal(t, []bool{}, longestIncreasingSubsequence([]int{}))
+	assert.Equal(t, []bool{true, true, true}, longestIncreasingSubsequence([]int{0, 1, 2}))
+	assert.Equal(t, []bool{true, false, true, true}, longestIncreasingSubsequence([]int{0, 3, 1, 2}))
+	assert.Equal(t, []bool{false, true, true}, longestIncreasingSubsequence([]int{2, 0, 1}))
+}
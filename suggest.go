@@ -0,0 +1,237 @@
+package codeowners
+
+import (
+	"math"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+)
+
+// AuthorMap maps the email addresses of git commit authors to the owners that
+// represent them in CODEOWNERS files, e.g. alice@example.com to @alice. Keys
+// should be lowercase, as email addresses are lowercased before lookup.
+type AuthorMap map[string]Owner
+
+var noreplyEmailRegexp = regexp.MustCompile(`\A(?:[0-9]+\+)?([a-zA-Z0-9\-]+)@users\.noreply\.github\.com\z`)
+
+// Owner returns the owner representing the author with the email address
+// provided. Authors that aren't in the map are represented by their GitHub
+// username if they use a GitHub noreply address, or by their email address
+// otherwise. Bots are never considered owners, so false is returned for them.
+func (m AuthorMap) Owner(email string) (Owner, bool) {
+	if owner, ok := m[strings.ToLower(email)]; ok {
+		return owner, true
+	}
+
+	if strings.Contains(email, "[bot]") {
+		return Owner{}, false
+	}
+	if match := noreplyEmailRegexp.FindStringSubmatch(email); match != nil {
+		return Owner{Value: match[1], Type: UsernameOwner}, true
+	}
+	if owner, err := MatchEmailOwner(email); err == nil {
+		return owner, true
+	}
+	return Owner{}, false
+}
+
+// OwnerCandidate is a potential owner for a set of files, based on how much
+// they've worked on them.
+type OwnerCandidate struct {
+	Owner Owner
+	// Score is the number of commits the candidate authored that touched the
+	// files, with each commit weighted by its age.
+	Score float64
+	// Commits is the unweighted number of commits.
+	Commits int
+}
+
+// OwnerSuggestion proposes owners for a group of unowned files.
+type OwnerSuggestion struct {
+	// Pattern is a CODEOWNERS pattern matching exactly the unowned files, which
+	// may be used with the candidates to create a new rule. It's empty for a
+	// file whose path contains "#", which can't be used in patterns.
+	Pattern string
+	// Paths lists the unowned files the suggestion covers.
+	Paths []string
+	// Candidates are sorted by descending score. It's empty if nobody who can
+	// be an owner has changed the files.
+	Candidates []OwnerCandidate
+}
+
+// SuggestOptions configure SuggestOwners.
+type SuggestOptions struct {
+	// Authors maps commit authors to owners. See AuthorMap.Owner for how
+	// authors that aren't in the map are handled.
+	Authors AuthorMap
+	// HalfLife is the age at which a commit counts for half as much as a
+	// commit made now. It defaults to 180 days.
+	HalfLife time.Duration
+	// Now is the time commit ages are measured from. It defaults to the
+	// current time.
+	Now time.Time
+	// Directories groups unowned files into the largest directories that
+	// contain only unowned files, rather than making a suggestion per file.
+	Directories bool
+}
+
+// SuggestOwners proposes owners for the files that the ruleset leaves
+// unowned, based on who authored the commits that changed them. Files is the
+// full list of files in the repository, and commits is its history (see
+// Repository.Log). Suggestions are sorted by pattern.
+func SuggestOwners(ruleset Ruleset, files []string, commits []Commit, opts SuggestOptions) ([]OwnerSuggestion, error) {
+	if opts.HalfLife <= 0 {
+		opts.HalfLife = 180 * 24 * time.Hour
+	}
+	if opts.Now.IsZero() {
+		opts.Now = time.Now()
+	}
+
+	var unowned []string
+	for _, file := range files {
+		owners, err := ruleset.owners(file)
+		if err != nil {
+			return nil, err
+		}
+		if len(owners) == 0 {
+			unowned = append(unowned, file)
+		}
+	}
+
+	groups := map[string]*OwnerSuggestion{}
+	groupOf := map[string]*OwnerSuggestion{}
+	unownedDirs := unownedDirectories(files, unowned)
+	for _, file := range unowned {
+		pattern := "/" + escapePattern(file)
+		if checkPatternPath(file) != nil {
+			pattern = ""
+		}
+		if opts.Directories {
+			if dir := topUnownedDirectory(file, unownedDirs); dir != "" {
+				pattern = "/" + escapePattern(dir) + "/"
+			}
+		}
+
+		// Files without a pattern are each suggested separately
+		key := pattern
+		if key == "" {
+			key = file
+		}
+		group, ok := groups[key]
+		if !ok {
+			group = &OwnerSuggestion{Pattern: pattern}
+			groups[key] = group
+		}
+		group.Paths = append(group.Paths, file)
+		groupOf[file] = group
+	}
+
+	type candidateKey struct {
+		group *OwnerSuggestion
+		owner string
+	}
+	candidates := map[candidateKey]*OwnerCandidate{}
+	for _, commit := range commits {
+		owner, ok := opts.Authors.Owner(commit.AuthorEmail)
+		if !ok {
+			continue
+		}
+		age := opts.Now.Sub(commit.AuthorTime)
+		if age < 0 {
+			age = 0
+		}
+		weight := math.Pow(0.5, float64(age)/float64(opts.HalfLife))
+
+		// A commit counts once for each group, however many of its files it changed
+		seen := map[*OwnerSuggestion]bool{}
+		for _, file := range commit.Files {
+			group := groupOf[file]
+			if group == nil || seen[group] {
+				continue
+			}
+			seen[group] = true
+
+			key := candidateKey{group: group, owner: owner.String()}
+			candidate, ok := candidates[key]
+			if !ok {
+				candidate = &OwnerCandidate{Owner: owner}
+				candidates[key] = candidate
+			}
+			candidate.Score += weight
+			candidate.Commits++
+		}
+	}
+	for key, candidate := range candidates {
+		key.group.Candidates = append(key.group.Candidates, *candidate)
+	}
+
+	suggestions := make([]OwnerSuggestion, 0, len(groups))
+	for _, group := range groups {
+		sort.Slice(group.Candidates, func(i, j int) bool {
+			a, b := group.Candidates[i], group.Candidates[j]
+			if a.Score != b.Score {
+				return a.Score > b.Score
+			}
+			return a.Owner.String() < b.Owner.String()
+		})
+		sort.Strings(group.Paths)
+		suggestions = append(suggestions, *group)
+	}
+	sort.Slice(suggestions, func(i, j int) bool {
+		a, b := suggestions[i], suggestions[j]
+		if a.Pattern != b.Pattern {
+			return a.Pattern < b.Pattern
+		}
+		return a.Paths[0] < b.Paths[0]
+	})
+	return suggestions, nil
+}
+
+// unownedDirectories returns the set of directories that only contain
+// unowned files.
+func unownedDirectories(files, unowned []string) map[string]bool {
+	total := map[string]int{}
+	for _, file := range files {
+		for dir := parentDir(file); dir != ""; dir = parentDir(dir) {
+			total[dir]++
+		}
+	}
+
+	unownedCount := map[string]int{}
+	for _, file := range unowned {
+		for dir := parentDir(file); dir != ""; dir = parentDir(dir) {
+			unownedCount[dir]++
+		}
+	}
+
+	dirs := map[string]bool{}
+	for dir, count := range unownedCount {
+		if count == total[dir] {
+			dirs[dir] = true
+		}
+	}
+	return dirs
+}
+
+// topUnownedDirectory returns the highest ancestor of the file that only
+// contains unowned files and can be written as a pattern, or an empty string if
+// there isn't one.
+func topUnownedDirectory(file string, unownedDirs map[string]bool) string {
+	top := ""
+	for dir := parentDir(file); dir != ""; dir = parentDir(dir) {
+		if unownedDirs[dir] && checkPatternPath(dir) == nil {
+			top = dir
+		}
+	}
+	return top
+}
+
+// parentDir returns the parent of a slash-separated relative path, or an empty
+// string for paths at the top level.
+func parentDir(path string) string {
+	if i := strings.LastIndexByte(path, '/'); i >= 0 {
+		return path[:i]
+	}
+	return ""
+}
@@ -0,0 +1,99 @@
+package codeowners
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSuggestOwners(t *testing.T) {
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	daysAgo := func(days int) time.Time { return now.Add(-time.Duration(days) * 24 * time.Hour) }
+
+	ruleset := mustParseFile(t, "/src/ @org/eng")
+	files := []string{"src/main.go", "tools/gen/gen.go", "tools/lint.sh", "scripts/deploy.sh", "scripts/README.md", "Makefile"}
+	commits := []Commit{
+		{AuthorEmail: "alice@example.com", AuthorTime: daysAgo(10), Files: []string{"tools/gen/gen.go", "tools/lint.sh"}},
+		{AuthorEmail: "bob@example.com", AuthorTime: daysAgo(400), Files: []string{"tools/lint.sh"}},
+		{AuthorEmail: "bob@example.com", AuthorTime: daysAgo(400), Files: []string{"tools/lint.sh", "src/main.go"}},
+		{AuthorEmail: "123+carol@users.noreply.github.com", AuthorTime: daysAgo(0), Files: []string{"scripts/deploy.sh"}},
+		{AuthorEmail: "49699333+dependabot[bot]@users.noreply.github.com", AuthorTime: daysAgo(0), Files: []string{"Makefile"}},
+		{AuthorEmail: "Dave@Example.com", AuthorTime: daysAgo(180), Files: []string{"scripts/README.md", "src/main.go"}},
+	}
+	authors := AuthorMap{"dave@example.com": {Value: "org/docs", Type: TeamOwner}}
+
+	t.Run("files", func(t *testing.T) {
+		suggestions, err := SuggestOwners(ruleset, files, commits, SuggestOptions{Authors: authors, Now: now})
+		require.NoError(t, err)
+
+		var patterns []string
+		for _, s := range suggestions {
+			patterns = append(patterns, s.Pattern)
+		}
+		assert.Equal(t, []string{"/Makefile", "/scripts/README.md", "/scripts/deploy.sh", "/tools/gen/gen.go", "/tools/lint.sh"}, patterns)
+
+		// Bots aren't suggested as owners
+		assert.Empty(t, suggestions[0].Candidates)
+
+		assert.Equal(t, []OwnerCandidate{
+			{Owner: Owner{Value: "org/docs", Type: TeamOwner}, Score: 0.5, Commits: 1},
+		}, suggestions[1].Candidates)
+		assert.Equal(t, []OwnerCandidate{
+			{Owner: Owner{Value: "carol", Type: UsernameOwner}, Score: 1, Commits: 1},
+		}, suggestions[2].Candidates)
+
+		// A recent commit outweighs two old ones
+		lint := suggestions[4].Candidates
+		require.Len(t, lint, 2)
+		assert.Equal(t, "alice@example.com", lint[0].Owner.Value)
+		assert.Equal(t, "bob@example.com", lint[1].Owner.Value)
+		assert.Equal(t, 2, lint[1].Commits)
+		assert.Greater(t, lint[0].Score, lint[1].Score)
+	})
+
+	t.Run("directories", func(t *testing.T) {
+		suggestions, err := SuggestOwners(ruleset, files, commits, SuggestOptions{Authors: authors, Now: now, Directories: true})
+		require.NoError(t, err)
+		require.Len(t, suggestions, 3)
+
+		assert.Equal(t, "/Makefile", suggestions[0].Pattern)
+		assert.Equal(t, "/scripts/", suggestions[1].Pattern)
+		assert.Equal(t, []string{"scripts/README.md", "scripts/deploy.sh"}, suggestions[1].Paths)
+		assert.Equal(t, "carol", suggestions[1].Candidates[0].Owner.Value)
+
+		// Alice's commit touched two files in tools/, but only counts once
+		assert.Equal(t, "/tools/", suggestions[2].Pattern)
+		assert.Equal(t, 1, suggestions[2].Candidates[0].Commits)
+	})
+}
+
+// Suggested patterns must parse and match the files they were suggested for
+func TestSuggestOwnersPatternsMatchPaths(t *testing.T) {
+	files := []string{"docs/with space.md", "glob*.txt", "dir/only/file"}
+	suggestions, err := SuggestOwners(Ruleset{}, files, nil, SuggestOptions{})
+	require.NoError(t, err)
+
+	for _, s := range suggestions {
+		ruleset := mustParseFile(t, s.Pattern+" @owner")
+		for _, path := range s.Paths {
+			rule, err := ruleset.Match(path)
+			require.NoError(t, err)
+			assert.NotNil(t, rule, "pattern %s should match %s", s.Pattern, path)
+		}
+	}
+}
+
+func TestSuggestOwnersHash(t *testing.T) {
+	files := []string{"a#b/c.txt", "a#b/d/e.txt", "f#g.txt"}
+	suggestions, err := SuggestOwners(Ruleset{}, files, nil, SuggestOptions{Directories: true})
+	require.NoError(t, err)
+
+	// Directories containing "#" can't be patterns, nor can files
+	require.Len(t, suggestions, 3)
+	for i, path := range []string{"a#b/c.txt", "a#b/d/e.txt", "f#g.txt"} {
+		assert.Equal(t, "", suggestions[i].Pattern)
+		assert.Equal(t, []string{path}, suggestions[i].Paths)
+	}
+}
@@ -0,0 +1,102 @@
+package codeowners
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// Diagnostic is a problem found in a line of a CODEOWNERS file.
+type Diagnostic struct {
+	LineNumber int
+	Message    string
+}
+
+// String returns the diagnostic prefixed with its line number.
+func (d Diagnostic) String() string {
+	return fmt.Sprintf("line %d: %s", d.LineNumber, d.Message)
+}
+
+// ValidateFile strictly checks a CODEOWNERS file against the rules GitHub
+// enforces. It reports lines GitHub skips because they're invalid (including
+// those with owners it doesn't recognise), and patterns using syntax GitHub
+// doesn't support, such as "!" negation, character ranges, and escaped "#"
+// characters, whose meaning may differ between GitHub and this library. The
+// file is always checked as the GitHub dialect, but WithOwnerMatchers may be
+// passed as an option. An error is only returned if the file can't be read.
+func ValidateFile(f io.Reader, options ...ParseOption) ([]Diagnostic, error) {
+	opts := parseOptions{}
+	for _, opt := range options {
+		opt(&opts)
+	}
+	opts.dialect = GitHub
+	if opts.ownerMatchers == nil {
+		opts.ownerMatchers = DefaultOwnerMatchers
+	}
+
+	var diagnostics []Diagnostic
+	scanner := bufio.NewScanner(f)
+	lineNo := 0
+	for scanner.Scan() {
+		lineNo++
+		line := strings.TrimSpace(scanner.Text())
+		if len(line) == 0 || line[0] == '#' {
+			continue
+		}
+
+		for _, msg := range checkGitHubPattern(line) {
+			diagnostics = append(diagnostics, Diagnostic{LineNumber: lineNo, Message: msg})
+		}
+		// Negated patterns fail to parse, but they've already been reported
+		if line[0] == '!' {
+			continue
+		}
+		if _, err := parseRule(line, opts); err != nil {
+			diagnostics = append(diagnostics, Diagnostic{
+				LineNumber: lineNo,
+				Message:    fmt.Sprintf("%v, so GitHub skips the line", err),
+			})
+		}
+	}
+	return diagnostics, scanner.Err()
+}
+
+// checkGitHubPattern looks for syntax GitHub doesn't support in the pattern at
+// the start of a line.
+func checkGitHubPattern(line string) []string {
+	var (
+		msgs     []string
+		brackets bool
+		braces   bool
+		escaped  bool
+	)
+	for i, ch := range line {
+		if ch == '#' {
+			if escaped {
+				msgs = append(msgs, `GitHub doesn't support escaping "#" with a backslash, so the rest of the line is a comment`)
+			}
+			break
+		}
+		if escaped {
+			escaped = false
+			continue
+		}
+
+		switch {
+		case ch == '\\':
+			escaped = true
+		case isWhitespace(ch):
+			return msgs
+		case ch == '!' && i == 0:
+			msgs = append(msgs, `GitHub doesn't support negating patterns with "!", so it skips the line`)
+		case (ch == '[' || ch == ']') && !brackets:
+			brackets = true
+			msgs = append(msgs, "GitHub doesn't support character ranges, so square brackets may not match as expected")
+		case (ch == '{' || ch == '}') && !braces:
+			braces = true
+			msgs = append(msgs, "GitHub doesn't support brace expansion, so braces may not match as expected")
+		}
+	}
+	return msgs
+}
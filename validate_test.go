@@ -0,0 +1,44 @@
+package codeowners
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestValidateFile(t *testing.T) {
+	file := `# Comment
+* @org/everyone
+!/vendor/
+/src/[abc]/ @alice
+*.{js,ts} @bob
+/docs/\#intro.md @carol
+/lib/ org/team
+/ok\ dir/ @dave # Escaped spaces are fine
+/literal/\[draft\]/ @erin
+`
+	diagnostics, err := ValidateFile(bytes.NewBufferString(file))
+	require.NoError(t, err)
+
+	var got []string
+	for _, d := range diagnostics {
+		got = append(got, d.String())
+	}
+	assert.Equal(t, []string{
+		`line 3: GitHub doesn't support negating patterns with "!", so it skips the line`,
+		"line 4: GitHub doesn't support character ranges, so square brackets may not match as expected",
+		"line 5: GitHub doesn't support brace expansion, so braces may not match as expected",
+		`line 6: GitHub doesn't support escaping "#" with a backslash, so the rest of the line is a comment`,
+		"line 7: invalid owner format 'org/team' at position 7, so GitHub skips the line",
+	}, got)
+}
+
+func TestValidateFileOwnerMatchers(t *testing.T) {
+	matchers := []OwnerMatcher{OwnerMatchFunc(MatchUsernameOwner)}
+	diagnostics, err := ValidateFile(bytes.NewBufferString("* @alice\n/docs/ docs@example.com\n"), WithOwnerMatchers(matchers))
+	require.NoError(t, err)
+	require.Len(t, diagnostics, 1)
+	assert.Equal(t, 2, diagnostics[0].LineNumber)
+}
@@ -0,0 +1,83 @@
+package codeowners
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// WriteFile writes a ruleset to w in the CODEOWNERS file format, one rule per
+// line with owners aligned in a column. Rule comments are preserved, but line
+// numbers are ignored. GitLab section headers are written before the first
+// rule of each section.
+func WriteFile(w io.Writer, ruleset Ruleset) error {
+	width := 0
+	for _, rule := range ruleset {
+		if len(rule.Owners) > 0 && len(rule.RawPattern()) > width {
+			width = len(rule.RawPattern())
+		}
+	}
+
+	bw := bufio.NewWriter(w)
+	var section *Section
+	for i, rule := range ruleset {
+		if rule.Section != nil && rule.Section != section {
+			if i > 0 {
+				fmt.Fprintln(bw)
+			}
+			if _, err := fmt.Fprintln(bw, rule.Section.String()); err != nil {
+				return err
+			}
+		}
+		section = rule.Section
+
+		line := rule.RawPattern()
+		if strings.Contains(line, "#") {
+			return fmt.Errorf("pattern %q contains \"#\", which would start a comment", line)
+		}
+		if rule.Excluded {
+			line = "!" + line
+		}
+		if len(rule.Owners) > 0 {
+			owners := make([]string, len(rule.Owners))
+			for i, o := range rule.Owners {
+				owners[i] = o.String()
+			}
+			line = fmt.Sprintf("%-*s  %s", width, line, strings.Join(owners, " "))
+		}
+		if rule.Comment != "" {
+			line += " # " + rule.Comment
+		}
+		if _, err := fmt.Fprintln(bw, line); err != nil {
+			return err
+		}
+	}
+	return bw.Flush()
+}
+
+// escapePattern escapes the characters in a path that have a special meaning
+// in CODEOWNERS patterns, so the pattern matches the path literally. Paths
+// containing "#" can't be escaped, so they should be checked with
+// checkPatternPath first.
+func escapePattern(path string) string {
+	var b strings.Builder
+	for i, ch := range path {
+		// A leading "!" would negate the pattern
+		if strings.ContainsRune("\\*? \t[]{}", ch) || (ch == '!' && i == 0) {
+			b.WriteByte('\\')
+		}
+		b.WriteRune(ch)
+	}
+	return b.String()
+}
+
+// checkPatternPath checks that a path can be written in a CODEOWNERS pattern.
+// GitHub starts a comment at any "#", even an escaped one, so paths containing
+// it can't be.
+func checkPatternPath(path string) error {
+	if strings.Contains(path, "#") {
+		return fmt.Errorf("%q contains \"#\", which can't be used in CODEOWNERS patterns", path)
+	}
+	return nil
+}
@@ -0,0 +1,104 @@
+package codeowners
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWriteFile(t *testing.T) {
+	ruleset, err := ParseFile(bytes.NewBufferString("* @org/everyone\n/docs/ @org/docs docs@example.com # Docs team\n/vendor/\n"))
+	require.NoError(t, err)
+
+	var buf bytes.Buffer
+	require.NoError(t, WriteFile(&buf, ruleset))
+	assert.Equal(t, ""+
+		"*       @org/everyone\n"+
+		"/docs/  @org/docs docs@example.com # Docs team\n"+
+		"/vendor/\n", buf.String())
+
+	parsed, err := ParseFile(&buf)
+	require.NoError(t, err)
+	require.Len(t, parsed, 3)
+	for i := range ruleset {
+		assert.Equal(t, ruleset[i].RawPattern(), parsed[i].RawPattern())
+		assert.Equal(t, ruleset[i].Owners, parsed[i].Owners)
+		assert.Equal(t, ruleset[i].Comment, parsed[i].Comment)
+	}
+}
+
+func TestEscapePattern(t *testing.T) {
+	path := "docs/a file 1[draft]*.md"
+	rule, err := NewRule("/"+escapePattern(path), nil)
+	require.NoError(t, err)
+
+	match, err := rule.Match(path)
+	require.NoError(t, err)
+	assert.True(t, match)
+
+	match, err = rule.Match("docs/a file 1d.md")
+	require.NoError(t, err)
+	assert.False(t, match)
+
+	// Escaped patterns survive being written and parsed again
+	path = "!notes/日本語 {v1},=&$%.md"
+	ruleset, err := ParseFile(bytes.NewBufferString(escapePattern(path) + " @user\n"))
+	require.NoError(t, err)
+	require.Len(t, ruleset, 1)
+	match, err = ruleset[0].Match(path)
+	require.NoError(t, err)
+	assert.True(t, match)
+}
+
+func TestWriteFileEscapedPatterns(t *testing.T) {
+	paths := []string{"docs/a file.md", "src/*/[x]/{a,b}?", "!important", `back\slash`, "tab\there"}
+	var ruleset Ruleset
+	for _, path := range paths {
+		rule, err := NewRule("/"+escapePattern(path), []Owner{{Value: "user", Type: UsernameOwner}})
+		require.NoError(t, err)
+		ruleset = append(ruleset, rule)
+	}
+
+	var buf bytes.Buffer
+	require.NoError(t, WriteFile(&buf, ruleset))
+	parsed, err := ParseFile(&buf)
+	require.NoError(t, err)
+	require.Len(t, parsed, len(paths))
+	for i, path := range paths {
+		assert.Equal(t, ruleset[i].RawPattern(), parsed[i].RawPattern())
+		assert.Empty(t, parsed[i].Comment)
+		match, err := parsed[i].Match(path)
+		require.NoError(t, err)
+		assert.True(t, match, path)
+	}
+}
+
+func TestWriteFileRejectsHash(t *testing.T) {
+	// GitHub treats "#" as the start of a comment even when it's escaped
+	assert.Error(t, checkPatternPath("pkg/c#"))
+	assert.NoError(t, checkPatternPath("pkg/c"))
+
+	rule, err := NewRule("/pkg/c#/", nil)
+	require.NoError(t, err)
+	err = WriteFile(&bytes.Buffer{}, Ruleset{rule})
+	assert.EqualError(t, err, `pattern "/pkg/c#/" contains "#", which would start a comment`)
+}
+
+func TestWriteFileSections(t *testing.T) {
+	ruleset, err := ParseFile(bytes.NewBufferString("* @a\n[Docs] @docs\ndocs/\n^[Go][2] @b\n*.go\nmain.go @c\n"), WithDialect(GitLab))
+	require.NoError(t, err)
+
+	var buf bytes.Buffer
+	require.NoError(t, WriteFile(&buf, ruleset))
+	assert.Equal(t, ""+
+		"*        @a\n"+
+		"\n"+
+		"[Docs] @docs\n"+
+		"docs/    @docs\n"+
+		"\n"+
+		"^[Go][2] @b\n"+
+		"*.go     @b\n"+
+		"main.go  @c\n", buf.String())
+}